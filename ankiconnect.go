@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const ankiConnectVersion = 6
+
+// AnkiConnect is a client for the JSON API of the AnkiConnect add-on.
+type AnkiConnect struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewAnkiConnect(url string) *AnkiConnect {
+	return &AnkiConnect{
+		URL:    url,
+		Client: http.DefaultClient,
+	}
+}
+
+func (ac *AnkiConnect) invoke(action string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"action":  action,
+		"version": ankiConnectVersion,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest("POST", ac.URL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ac.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	} else if resp.StatusCode != 200 {
+		return fmt.Errorf("ankiconnect: %s", resp.Status)
+	}
+
+	var res struct {
+		Result json.RawMessage
+		Error  *string
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	} else if res.Error != nil {
+		return fmt.Errorf("%s: %s", action, *res.Error)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, result)
+}
+
+func (ac *AnkiConnect) DeckNames() ([]string, error) {
+	var names []string
+	return names, ac.invoke("deckNames", nil, &names)
+}
+
+func (ac *AnkiConnect) CreateDeck(deck string) error {
+	return ac.invoke("createDeck", map[string]interface{}{
+		"deck": deck,
+	}, nil)
+}
+
+func (ac *AnkiConnect) ModelNames() ([]string, error) {
+	var names []string
+	return names, ac.invoke("modelNames", nil, &names)
+}
+
+func (ac *AnkiConnect) CreateModel(noteType NoteType, front, back, css string) error {
+	return ac.invoke("createModel", map[string]interface{}{
+		"modelName":     noteType.Name,
+		"inOrderFields": noteType.Columns,
+		"css":           css,
+		"isCloze":       false,
+		"cardTemplates": []map[string]string{{
+			"Name":  "Card 1",
+			"Front": front,
+			"Back":  back,
+		}},
+	}, nil)
+}
+
+func (ac *AnkiConnect) StoreMediaFile(filename string, data []byte) error {
+	return ac.invoke("storeMediaFile", map[string]interface{}{
+		"filename": filename,
+		"data":     data,
+	}, nil)
+}
+
+func (ac *AnkiConnect) FindNotes(query string) ([]int64, error) {
+	var ids []int64
+	return ids, ac.invoke("findNotes", map[string]interface{}{
+		"query": query,
+	}, &ids)
+}
+
+type AnkiNoteInfo struct {
+	NoteID    int64
+	ModelName string
+	Fields    map[string]struct {
+		Value string
+		Order int
+	}
+}
+
+func (ac *AnkiConnect) NotesInfo(ids []int64) ([]AnkiNoteInfo, error) {
+	var notes []AnkiNoteInfo
+	return notes, ac.invoke("notesInfo", map[string]interface{}{
+		"notes": ids,
+	}, &notes)
+}
+
+func (ac *AnkiConnect) AddNote(deck string, model string, fields map[string]string, tags []string) error {
+	return ac.invoke("addNote", map[string]interface{}{
+		"note": map[string]interface{}{
+			"deckName":  deck,
+			"modelName": model,
+			"fields":    fields,
+			"tags":      tags,
+			"options": map[string]interface{}{
+				"allowDuplicate": true,
+			},
+		},
+	}, nil)
+}
+
+func (ac *AnkiConnect) UpdateNoteFields(id int64, fields map[string]string) error {
+	return ac.invoke("updateNoteFields", map[string]interface{}{
+		"note": map[string]interface{}{
+			"id":     id,
+			"fields": fields,
+		},
+	}, nil)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var regionColor = color.RGBA{R: 0xe0, G: 0x10, B: 0x10, A: 0xff}
+
+const regionBorder = 3
+
+// annotateImage writes a copy of the image at src to dest with every region
+// outlined.
+func annotateImage(src string, dest string, regions []image.Rectangle) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	fill := image.NewUniform(regionColor)
+	for _, r := range regions {
+		r = r.Add(img.Bounds().Min).Intersect(out.Bounds())
+		if r.Empty() {
+			continue
+		}
+		for _, edge := range []image.Rectangle{
+			image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+regionBorder),
+			image.Rect(r.Min.X, r.Max.Y-regionBorder, r.Max.X, r.Max.Y),
+			image.Rect(r.Min.X, r.Min.Y, r.Min.X+regionBorder, r.Max.Y),
+			image.Rect(r.Max.X-regionBorder, r.Min.Y, r.Max.X, r.Max.Y),
+		} {
+			draw.Draw(out, edge.Intersect(r), fill, image.Point{}, draw.Src)
+		}
+	}
+
+	w, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return png.Encode(w, out)
+}
+
+// annotatedName returns the file name of the copy of imageName with the given
+// regions outlined. As images are shared between questions, the name depends on
+// the regions too.
+func annotatedName(imageName string, regions []image.Rectangle) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(regions)))
+	return strings.TrimSuffix(imageName, filepath.Ext(imageName)) +
+		"-" + hex.EncodeToString(sum[:4]) + ".png"
+}
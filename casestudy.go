@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CaseStudyTab struct {
+	Label  string
+	Text   string
+	Images QuestionImages
+}
+
+// CaseStudy is the scenario shared by all questions of a case study.
+type CaseStudy struct {
+	ID   string
+	Tabs []CaseStudyTab
+}
+
+func NewCaseStudyTab(textDB TextDB, label string, slide QuestionSlide) CaseStudyTab {
+	var texts []string
+	for i, text := range slide.Texts {
+		texts = append(texts, textDB.In(fmt.Sprintf("Texts[%d].Value", i)).HTML(text.Value))
+	}
+
+	return CaseStudyTab{
+		Label:  textDB.In("Label").HTML(label),
+		Text:   strings.Join(texts, "\n<br>\n"),
+		Images: slide.AllImages(),
+	}
+}
+
+func (cs *CaseStudy) Tag() string {
+	return "casestudy::" + cs.ID
+}
+
+func (cs *CaseStudy) HTML() string {
+	var b strings.Builder
+
+	b.WriteString(`<details class="case-study"><summary>Case study</summary>`)
+	for _, tab := range cs.Tabs {
+		fmt.Fprintf(&b, `<h4>%s</h4>`, tab.Label)
+		b.WriteString(tab.Text)
+		if len(tab.Images) > 0 {
+			b.WriteString("\n<br>\n")
+			b.WriteString(tab.Images.HTML())
+		}
+	}
+	b.WriteString(`</details>`)
+	return b.String()
+}
+
+// RecordContext holds what a record knows about its surroundings besides the
+// question itself.
+type RecordContext struct {
+	CaseStudy *CaseStudy
+	// Translation is the question in a second language, shown on the back.
+	Translation string
+	// Refs are the links given in the explanation.
+	Refs References
+}
+
+// newRecordContext returns the context of a question's record along with the
+// explanation, whose references are moved into the context.
+func newRecordContext(textDB TextDB, question Question) (RecordContext, string) {
+	html := textDB.In("Explanation.Value").HTML(question.Explanation.Value)
+	explanation, refs := extractReferences(html)
+	return RecordContext{Refs: refs}, explanation
+}
+
+func (rc *RecordContext) SetCaseStudy(cs *CaseStudy) {
+	rc.CaseStudy = cs
+}
+
+func (rc *RecordContext) SetTranslation(html string) {
+	rc.Translation = html
+}
+
+func (rc *RecordContext) References() References {
+	return rc.Refs
+}
+
+func (rc *RecordContext) ReferencesHTML() string {
+	return rc.Refs.HTML()
+}
+
+func (rc *RecordContext) CaseStudyHTML() string {
+	if rc.CaseStudy == nil {
+		return ""
+	}
+	return rc.CaseStudy.HTML()
+}
+
+func (rc *RecordContext) Tags() []string {
+	var tags []string
+	if rc.CaseStudy != nil {
+		tags = append(tags, rc.CaseStudy.Tag())
+	}
+	return tags
+}
@@ -75,10 +75,15 @@ func getSkillGroups(c *http.Client, dest string, test AssignedTest) ([]SkillGrou
 	return groups, json.Unmarshal(body, &groups)
 }
 
-func getTextDB(c *http.Client, dest string, test AssignedTest) (TextDB, error) {
+// getTextDB downloads the texts of a test in the given language, or in the
+// account's default language if it's empty.
+func getTextDB(c *http.Client, dest string, test AssignedTest, language string) (*TextDB, error) {
 	params := make(url.Values)
 	params.Set("test", test.Test)
 	params.Set("shortname", test.VendorTest)
+	if language != "" {
+		params.Set("lang", language)
+	}
 
 	req, _ := http.NewRequest(
 		"POST",
@@ -96,15 +101,18 @@ func getTextDB(c *http.Client, dest string, test AssignedTest) (TextDB, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
+	} else if resp.StatusCode != 200 {
+		return nil, fmt.Errorf(resp.Status)
 	}
 
-	err = os.WriteFile(filepath.Join(dest, "textdb.json"), body, 0o644)
-	if err != nil {
+	var texts TextDB
+	if err := json.Unmarshal(body, &texts); err != nil {
 		return nil, err
+	} else if language != "" && len(texts.texts) == 0 {
+		// Not written, so that produce doesn't take the language as dumped.
+		return nil, fmt.Errorf("MeasureUp has no texts in '%s'", language)
 	}
-
-	var texts TextDB
-	return texts, json.Unmarshal(body, &texts)
+	return &texts, os.WriteFile(filepath.Join(dest, textDBFile(language)), body, 0o644)
 }
 
 func getQuestion(c *http.Client, dest string, questionName string) (*Question, error) {
@@ -163,7 +171,7 @@ func getSlide(c *http.Client, dest string, slideName string) (*QuestionSlide, er
 	return &slide, json.Unmarshal(body, &slide)
 }
 
-func getImage(c *http.Client, dest string, imageName string) error {
+func getImage(c *http.Client, imageName string) ([]byte, error) {
 	req, _ := http.NewRequest(
 		"GET",
 		"https://pts.measureup.com/web/instances/MUP/"+imageName,
@@ -171,18 +179,18 @@ func getImage(c *http.Client, dest string, imageName string) error {
 	)
 	resp, err := c.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	} else if resp.StatusCode != 200 {
-		return fmt.Errorf(resp.Status)
+		return nil, fmt.Errorf(resp.Status)
 	}
 
-	return os.WriteFile(dest, body, 0o644)
+	return body, nil
 }
 
 type transport struct {
@@ -203,7 +211,45 @@ func sessionCookie(session string) (*url.URL, []*http.Cookie) {
 	}}
 }
 
-func dump(session string, testName string) ([]AssignedTest, error) {
+// dumpSlide downloads a slide along with its images and the given additional
+// ones, skipping images which are already in the store.
+func dumpSlide(
+	c *http.Client,
+	path string,
+	store *MediaStore,
+	slideName string,
+	images QuestionImages,
+) (*QuestionSlide, error) {
+	parts := strings.Split(slideName, "/")
+	slide, err := getSlide(
+		c,
+		filepath.Join(path, "slides", parts[len(parts)-1]+".json"),
+		slideName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	images = append(images, slide.AllImages()...)
+	for _, image := range images {
+		if _, ok := store.Lookup(image.Name); ok {
+			continue
+		}
+		log.Println("  ", image.Name)
+
+		buf, err := getImage(c, image.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := store.Put(image.Name, buf); err != nil {
+			return nil, err
+		}
+	}
+
+	return slide, nil
+}
+
+func dump(session string, testName string, languages []string) ([]AssignedTest, error) {
 	cookies, _ := cookiejar.New(nil)
 	cookies.SetCookies(sessionCookie(session))
 
@@ -211,7 +257,12 @@ func dump(session string, testName string) ([]AssignedTest, error) {
 		Jar:       cookies,
 		Transport: &transport{},
 	}
+	return dumpTest(c, testName, languages)
+}
 
+// dumpTest lists the assigned tests and downloads the one named testName, if
+// it isn't empty.
+func dumpTest(c *http.Client, testName string, languages []string) ([]AssignedTest, error) {
 	tests, err := getAssignedTests(c)
 	if err != nil {
 		return nil, err
@@ -223,6 +274,11 @@ func dump(session string, testName string) ([]AssignedTest, error) {
 	if testName == "" {
 		return tests, nil
 	}
+	for _, language := range languages {
+		if !languageCode.MatchString(language) {
+			return tests, fmt.Errorf("'%s' is not a language code like de or pt-BR", language)
+		}
+	}
 
 	idx := slices.IndexFunc(tests, func(test AssignedTest) bool {
 		return strings.EqualFold(test.VendorTest, testName)
@@ -242,7 +298,18 @@ func dump(session string, testName string) ([]AssignedTest, error) {
 	if err != nil {
 		return tests, err
 	}
-	_, err = getTextDB(c, path, test)
+	_, err = getTextDB(c, path, test, "")
+	if err != nil {
+		return tests, err
+	}
+	for _, language := range languages {
+		log.Printf("Texts in %s\n", language)
+		if _, err := getTextDB(c, path, test, language); err != nil {
+			return tests, fmt.Errorf("texts in %s: %v", language, err)
+		}
+	}
+
+	store, err := OpenMediaStore(path)
 	if err != nil {
 		return tests, err
 	}
@@ -262,50 +329,35 @@ func dump(session string, testName string) ([]AssignedTest, error) {
 				return tests, err
 			}
 
-			parts := strings.Split(question.StartSlide.Value, "/")
-			slide, err := getSlide(
-				c,
-				filepath.Join(path, "slides", parts[1]+".json"),
-				question.StartSlide.Value,
-			)
-			if err != nil {
-				return tests, err
-			}
-
+			var slides QuestionSlides
 			images := question.Images()
-			if slide.View.Image != "" {
-				images = append(images, QuestionImage{
-					Name: slide.View.Image, Alt: slide.View.Alt,
-				})
-			}
-			for _, image := range slide.Images {
-				images = append(images, QuestionImage{
-					Name: image.Image, Alt: image.Alt,
-				})
-			}
-
-			for _, image := range images {
-				log.Println("  ", image.Name)
-
-				parts := strings.Split(image.Name, "/")
-				ifn := qfn + "-" + parts[len(parts)-1]
-				err := getImage(
-					c,
-					filepath.Join(path, "images", ifn),
-					image.Name,
-				)
+			for _, slideName := range question.SlideNames() {
+				slide, err := dumpSlide(c, path, store, slideName, images)
 				if err != nil {
 					return tests, err
 				}
+				slides = append(slides, *slide)
+				images = nil
 			}
 
 			if groupQuestion.Type == "caseStudy" {
-				for _, opt := range slide.CaseStudy[0].Options {
+				opts, err := slides.Merged().CaseStudyOptions()
+				if err != nil {
+					// It's reported again by produce.
+					log.Printf("Skipping %s: %v\n", qfn, err)
+					continue
+				}
+				for _, opt := range opts {
 					if opt.CSContext != "" {
 						group.Questions = append(group.Questions, SkillGroupQuestion{
 							Name: groupQuestion.Name + "_" + opt.CSContext,
 							Type: "caseStudyQuestion",
 						})
+					} else if opt.View != "" {
+						_, err := dumpSlide(c, path, store, opt.View, nil)
+						if err != nil {
+							return tests, err
+						}
 					}
 				}
 			}
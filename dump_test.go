@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMeasureUp stands in for MeasureUp, answering every request with the
+// file whose name the URL ends with.
+type fakeMeasureUp map[string]string
+
+func (f fakeMeasureUp) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Request: req}
+	for name, content := range f {
+		if strings.HasSuffix(req.URL.Path, name) {
+			resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+			resp.Body = io.NopCloser(strings.NewReader(content))
+			return resp, nil
+		}
+	}
+	resp.Body = io.NopCloser(strings.NewReader(""))
+	return resp, nil
+}
+
+func TestDumpSkipsMalformedCaseStudies(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	c := &http.Client{Transport: fakeMeasureUp{
+		"getAssignedTestUsers.php": `[{"Test": "t1", "VendorTest": "T1", "ProductID": 1}]`,
+		"getTestSkillgroups.php": `[{"ID": 1, "Name": "Group One", "Questions": [
+			{"Name": "MUP/CS_1", "question_type": "caseStudy"},
+			{"Name": "MUP/Q_1", "question_type": "singleChoice"}
+		]}]`,
+		"obtainQuestions.php":     `{"s1": "Which one?"}`,
+		"questions/MUP/CS_1.json": `{"StartSlide": {"Value": "views/CS_1_s"}}`,
+		"views/CS_1_s.json":       `{}`,
+		"questions/MUP/Q_1.json":  `{"Stem": {"Value": "$$s1"}, "StartSlide": {"Value": "views/Q_1_s"}}`,
+		"views/Q_1_s.json":        `{"RadioButtons": [{"ID": "rb1", "Value": "$$s1"}]}`,
+	}}
+
+	if _, err := dumpTest(c, "T1", nil); err != nil {
+		t.Fatalf("dump failed on a malformed case study: %v", err)
+	}
+	for _, name := range []string{"questions/CS_1.json", "questions/Q_1.json", "slides/Q_1_s.json"} {
+		if _, err := os.Stat(filepath.Join("out", "dump", "t1", name)); err != nil {
+			t.Errorf("%s wasn't dumped: %v", name, err)
+		}
+	}
+}
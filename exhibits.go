@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+type ExhibitKind string
+
+const (
+	ExhibitImage ExhibitKind = "image"
+	ExhibitText  ExhibitKind = "text"
+	ExhibitTable ExhibitKind = "table"
+	ExhibitCode  ExhibitKind = "code"
+	ExhibitTabs  ExhibitKind = "tabs"
+)
+
+type ExhibitTab struct {
+	Label    string
+	Exhibits Exhibits
+}
+
+// Exhibit is a single piece of an exhibit, only the fields belonging to its
+// kind are set.
+type Exhibit struct {
+	Kind     ExhibitKind
+	Image    QuestionImage
+	Text     string
+	Language string
+	Header   []string
+	Rows     [][]string
+	Tabs     []ExhibitTab
+}
+
+type Exhibits []Exhibit
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func strs(v interface{}) []string {
+	var res []string
+	if a, ok := v.([]interface{}); ok {
+		for _, s := range a {
+			res = append(res, fmt.Sprint(s))
+		}
+	}
+	return res
+}
+
+// parseExhibits turns the loosely typed exhibit content of a question into
+// exhibits. Unknown shapes are skipped.
+func parseExhibits(content interface{}) Exhibits {
+	var res Exhibits
+
+	switch content := content.(type) {
+	case string:
+		if content != "" {
+			res = append(res, Exhibit{Kind: ExhibitText, Text: content})
+		}
+	case []interface{}:
+		for _, c := range content {
+			res = append(res, parseExhibits(c)...)
+		}
+	case map[string]interface{}:
+		switch {
+		case content["image"] != nil:
+			res = append(res, Exhibit{
+				Kind: ExhibitImage,
+				Image: QuestionImage{
+					Name: str(content["image"]),
+					Alt:  str(content["alt"]),
+				},
+			})
+		case content["code"] != nil:
+			res = append(res, Exhibit{
+				Kind:     ExhibitCode,
+				Text:     str(content["code"]),
+				Language: str(content["language"]),
+			})
+		case content["rows"] != nil || content["table"] != nil:
+			rows := content["rows"]
+			if rows == nil {
+				rows = content["table"]
+			}
+			e := Exhibit{
+				Kind:   ExhibitTable,
+				Header: strs(content["header"]),
+			}
+			if rows, ok := rows.([]interface{}); ok {
+				for _, row := range rows {
+					e.Rows = append(e.Rows, strs(row))
+				}
+			}
+			res = append(res, e)
+		case content["tabs"] != nil:
+			e := Exhibit{Kind: ExhibitTabs}
+			if tabs, ok := content["tabs"].([]interface{}); ok {
+				for _, tab := range tabs {
+					tab, _ := tab.(map[string]interface{})
+					e.Tabs = append(e.Tabs, ExhibitTab{
+						Label:    str(tab["label"]),
+						Exhibits: parseExhibits(tab["content"]),
+					})
+				}
+			}
+			res = append(res, e)
+		case content["text"] != nil || content["html"] != nil:
+			text := str(content["text"])
+			if text == "" {
+				text = str(content["html"])
+			}
+			res = append(res, Exhibit{Kind: ExhibitText, Text: text})
+		}
+	}
+	return res
+}
+
+// Walk calls fn for every exhibit including the ones nested in tabs.
+func (es Exhibits) Walk(fn func(e *Exhibit)) {
+	for i := range es {
+		fn(&es[i])
+		for j := range es[i].Tabs {
+			es[i].Tabs[j].Exhibits.Walk(fn)
+		}
+	}
+}
+
+func (es Exhibits) Images() QuestionImages {
+	var res QuestionImages
+	es.Walk(func(e *Exhibit) {
+		if e.Kind == ExhibitImage {
+			res = append(res, e.Image)
+		}
+	})
+	return res
+}
+
+// Resolve replaces the TextDB keys of all texts by their values.
+func (es Exhibits) Resolve(textDB TextDB) {
+	textDB = textDB.In("Exhibit.Content")
+	get := textDB.Resolve
+	// Alt texts and code are escaped when rendered.
+	getText := func(s string) string {
+		if strings.HasPrefix(s, "$$") {
+			return textDB.Get(s)
+		}
+		return s
+	}
+
+	es.Walk(func(e *Exhibit) {
+		e.Image.Alt = getText(e.Image.Alt)
+		if e.Kind == ExhibitCode {
+			e.Text = getText(e.Text)
+		} else {
+			e.Text = get(e.Text)
+		}
+		for i := range e.Header {
+			e.Header[i] = get(e.Header[i])
+		}
+		for _, row := range e.Rows {
+			for i := range row {
+				row[i] = get(row[i])
+			}
+		}
+		for i := range e.Tabs {
+			e.Tabs[i].Label = get(e.Tabs[i].Label)
+		}
+	})
+}
+
+func (e Exhibit) HTML() string {
+	var b strings.Builder
+
+	switch e.Kind {
+	case ExhibitImage:
+		b.WriteString(QuestionImages{e.Image}.HTML())
+	case ExhibitText:
+		fmt.Fprintf(&b, `<div class="exhibit text">%s</div>`, e.Text)
+	case ExhibitCode:
+		fmt.Fprintf(&b, `<pre class="exhibit code" data-language="%s"><code>%s</code></pre>`,
+			escapeTemplate(html.EscapeString(e.Language)),
+			escapeTemplate(html.EscapeString(e.Text)),
+		)
+	case ExhibitTable:
+		b.WriteString(`<table class="exhibit table">`)
+		if len(e.Header) > 0 {
+			b.WriteString(`<tr>`)
+			for _, cell := range e.Header {
+				fmt.Fprintf(&b, `<th>%s</th>`, cell)
+			}
+			b.WriteString(`</tr>`)
+		}
+		for _, row := range e.Rows {
+			b.WriteString(`<tr>`)
+			for _, cell := range row {
+				fmt.Fprintf(&b, `<td>%s</td>`, cell)
+			}
+			b.WriteString(`</tr>`)
+		}
+		b.WriteString(`</table>`)
+	case ExhibitTabs:
+		b.WriteString(`<div class="exhibit tabs">`)
+		for i, tab := range e.Tabs {
+			open := ""
+			if i == 0 {
+				open = " open"
+			}
+			fmt.Fprintf(&b, `<details%s><summary>%s</summary>%s</details>`,
+				open, tab.Label, tab.Exhibits.HTML())
+		}
+		b.WriteString(`</div>`)
+	}
+	return b.String()
+}
+
+func (es Exhibits) HTML() string {
+	var parts []string
+	for _, e := range es {
+		if s := e.HTML(); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n<br>\n")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseExhibits(t *testing.T) {
+	var content interface{}
+	decode(t, `[
+		{"image": "img/a.png", "alt": "A"},
+		"Plain text",
+		{"code": "Get-Item", "language": "powershell"},
+		{"header": ["Name", "Size"], "rows": [["a", 1], ["b", 2]]},
+		{"tabs": [{"label": "One", "content": [{"html": "<b>x</b>"}]}]},
+		{"unknown": true}
+	]`, &content)
+
+	want := Exhibits{
+		{Kind: ExhibitImage, Image: QuestionImage{Name: "img/a.png", Alt: "A"}},
+		{Kind: ExhibitText, Text: "Plain text"},
+		{Kind: ExhibitCode, Text: "Get-Item", Language: "powershell"},
+		{Kind: ExhibitTable, Header: []string{"Name", "Size"}, Rows: [][]string{{"a", "1"}, {"b", "2"}}},
+		{Kind: ExhibitTabs, Tabs: []ExhibitTab{{
+			Label:    "One",
+			Exhibits: Exhibits{{Kind: ExhibitText, Text: "<b>x</b>"}},
+		}}},
+	}
+	got := parseExhibits(content)
+	if !reflect.DeepEqual(got, want) {
+		a, _ := json.Marshal(got)
+		b, _ := json.Marshal(want)
+		t.Errorf("exhibits = %s\nwant %s", a, b)
+	}
+	if images := got.Images(); len(images) != 1 || images[0].Name != "img/a.png" {
+		t.Errorf("images = %v, want the image exhibit", images)
+	}
+}
+
+func TestExhibitHTML(t *testing.T) {
+	tests := []struct {
+		exhibit Exhibit
+		want    string
+	}{
+		{
+			Exhibit{Kind: ExhibitText, Text: "<b>x</b>"},
+			`<div class="exhibit text"><b>x</b></div>`,
+		},
+		{
+			Exhibit{Kind: ExhibitCode, Text: "if a < b {}", Language: "go"},
+			`<pre class="exhibit code" data-language="go"><code>if a &lt; b {}</code></pre>`,
+		},
+		{
+			Exhibit{Kind: ExhibitCode, Text: "dir C:\\Users `${x}`", Language: "cmd"},
+			`<pre class="exhibit code" data-language="cmd"><code>dir C:&#92;Users &#96;$&#123;x}&#96;</code></pre>`,
+		},
+		{
+			Exhibit{Kind: ExhibitImage, Image: QuestionImage{Name: "a.png", Alt: `Say "hi" <b>\`}},
+			`<img src="a.png" alt="Say &#34;hi&#34; &lt;b&gt;&#92;" class="exhibit">`,
+		},
+		{
+			Exhibit{Kind: ExhibitTable, Header: []string{"H"}, Rows: [][]string{{"c"}}},
+			`<table class="exhibit table"><tr><th>H</th></tr><tr><td>c</td></tr></table>`,
+		},
+		{
+			Exhibit{Kind: ExhibitTabs, Tabs: []ExhibitTab{
+				{Label: "One", Exhibits: Exhibits{{Kind: ExhibitText, Text: "1"}}},
+				{Label: "Two"},
+			}},
+			`<div class="exhibit tabs"><details open><summary>One</summary><div class="exhibit text">1</div></details>` +
+				`<details><summary>Two</summary></details></div>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.exhibit.HTML(); got != tt.want {
+			t.Errorf("%s exhibit:\n got %s\nwant %s", tt.exhibit.Kind, got, tt.want)
+		}
+	}
+}
+
+func TestExhibitsResolve(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{"t1": `<p style="color:red">Key</p>`})
+	es := Exhibits{
+		{Kind: ExhibitText, Text: "$$t1"},
+		{Kind: ExhibitText, Text: `Literal<script>alert(1)</script> <i>text`},
+		{Kind: ExhibitTable, Header: []string{"<b onclick=x>H</b>"}, Rows: [][]string{{"a`b", "$$t1"}}},
+		{Kind: ExhibitTabs, Tabs: []ExhibitTab{{Label: "<u>Tab"}}},
+		{Kind: ExhibitCode, Text: "<b>as is</b>"},
+	}
+	es.Resolve(textDB)
+
+	want := Exhibits{
+		{Kind: ExhibitText, Text: "<p>Key</p>"},
+		{Kind: ExhibitText, Text: "Literal <i>text</i>"},
+		{Kind: ExhibitTable, Header: []string{"<b>H</b>"}, Rows: [][]string{{"a&#96;b", "<p>Key</p>"}}},
+		{Kind: ExhibitTabs, Tabs: []ExhibitTab{{Label: "<u>Tab</u>"}}},
+		{Kind: ExhibitCode, Text: "<b>as is</b>"},
+	}
+	if !reflect.DeepEqual(es, want) {
+		a, _ := json.Marshal(es)
+		b, _ := json.Marshal(want)
+		t.Errorf("exhibits = %s\nwant %s", a, b)
+	}
+	if misses := textDB.Misses(); len(misses) > 0 {
+		t.Errorf("literal texts were looked up: %v", misses)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuestionParts is what every record has in common, for formats which render
+// the questions themselves instead of using the card templates.
+type QuestionParts struct {
+	ID          string
+	Kind        string
+	Group       SkillGroup
+	Text        string
+	Explanation string
+	Exhibits    Exhibits
+	Context     *RecordContext
+	// Front is the HTML of what has to be answered besides the text, Back
+	// the one of the answer.
+	Front string
+	Back  string
+}
+
+func listHTML(tag string, items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<" + tag + ">")
+	for _, item := range items {
+		fmt.Fprintf(&b, "<li>%s</li>", item)
+	}
+	b.WriteString("</" + tag + ">")
+	return b.String()
+}
+
+// partsOf splits a record into its parts.
+func partsOf(record Record) QuestionParts {
+	switch r := record.(type) {
+	case *SingleChoice:
+		var answers []string
+		if r.Answer > 0 && r.Answer <= len(r.Options) {
+			answers = append(answers, r.Options[r.Answer-1])
+		}
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "singleChoice", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: listHTML("ul", r.Options),
+			Back:  listHTML("ul", answers),
+		}
+	case *MultipleChoice:
+		var answers []string
+		for _, n := range r.Answers {
+			if n > 0 && n <= len(r.Options) {
+				answers = append(answers, r.Options[n-1])
+			}
+		}
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "multipleChoice", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: listHTML("ul", r.Options),
+			Back:  listHTML("ul", answers),
+		}
+	case *LiveScreen:
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "liveScreen", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: r.ImageHTML() + r.screensHTML(func(i int) string {
+				return "<li>" + strings.Join(r.Options[i], " / ") + "</li>"
+			}),
+			Back: r.AnswersHTML(),
+		}
+	case *ContentTable:
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "contentTable", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: r.TableHTML(false),
+			Back:  r.TableHTML(true),
+		}
+	case *BuildList:
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "buildList", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: r.ItemsHTML(),
+			Back:  r.StepsHTML(),
+		}
+	case *SelectPlaceMup:
+		var zones, answers []string
+		for i, opts := range r.Options {
+			zones = append(zones, strings.Join(opts, " / "))
+			answer := "?"
+			if n := r.Answers[i]; n >= 0 && n < len(opts) {
+				answer = opts[n]
+			}
+			answers = append(answers, answer)
+		}
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "selectPlaceMup", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: r.ImageHTML() + listHTML("ol", zones),
+			Back:  listHTML("ol", answers),
+		}
+	case *DragDrop:
+		var targets, answers []string
+		for _, a := range r.Answers {
+			targets = append(targets, a.Target)
+
+			var sources []string
+			for _, n := range a.Sources {
+				if n > 0 && n <= len(r.Options) {
+					sources = append(sources, r.Options[n-1])
+				}
+			}
+			answers = append(answers, a.Target+" → "+strings.Join(sources, ", "))
+		}
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "dragDrop", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: listHTML("ul", r.Options) + listHTML("ul", targets),
+			Back:  listHTML("ul", answers),
+		}
+	case *HotSpot:
+		back := r.Image.HTML("image")
+		if r.AnswerImageName != "" {
+			back = QuestionImage{Name: r.AnswerImageName, Alt: r.Image.Alt}.HTML("image")
+		}
+		return QuestionParts{
+			Context: &r.RecordContext, ID: r.ID, Kind: "hotspot", Group: r.Group,
+			Text: r.Text, Explanation: r.Explanation, Exhibits: r.Exhibits,
+			Front: r.Image.HTML("image"),
+			Back:  back,
+		}
+	}
+	panic(fmt.Sprintf("unknown record %T", record))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var selfClosingTag = regexp.MustCompile(`(?i)<(br|hr|img|input|col|wbr)\b([^<>]*?)\s*/>`)
+
+// normalizeText normalizes a text of the TextDB, which MeasureUp writes with
+// void elements XHTML-style. Backslashes are left as they're decoded from the
+// JSON, e.g. in C:\Windows, \\server\share or a regex like ^\d+$.
+func normalizeText(s string) string {
+	return selfClosingTag.ReplaceAllString(s, "<$1$2>")
+}
+
+var (
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlTag     = regexp.MustCompile(
+		`<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[^\s"'<>/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'<>=]+))?)*)\s*/?>`,
+	)
+	htmlAttr = regexp.MustCompile(
+		`([^\s"'<>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'<>=]+)))?`,
+	)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// allowedTags maps the tags kept in texts to the attributes kept on them,
+// everything else is dropped, e.g. inline styles which would override the
+// card's styling.
+var allowedTags = map[string][]string{
+	"a": {"href"}, "img": {"src", "alt", "width", "height"},
+	"b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil,
+	"sub": nil, "sup": nil, "code": nil, "kbd": nil, "samp": nil, "var": nil,
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil, "pre": nil,
+	"blockquote": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil,
+	"h6": nil, "ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil,
+	"dd": nil, "table": nil, "thead": nil, "tbody": nil, "tfoot": nil,
+	"tr": nil, "th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+}
+
+// droppedTags are removed along with their content.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "title": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// containerTags only contain other elements, whitespace between those is
+// dropped.
+var containerTags = map[string]bool{
+	"ul": true, "ol": true, "dl": true, "table": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true,
+}
+
+// sanitizeHTML turns a fragment of a text into HTML which is safe to be put
+// into the cards: disallowed tags and attributes are removed, unclosed tags
+// are closed, list items without a list are put into one and whitespace is
+// collapsed outside of <pre>. Finally, backticks, ${ and backslashes are
+// replaced by entities, as the options are put into template literals.
+func sanitizeHTML(s string) string {
+	s = htmlComment.ReplaceAllString(s, "")
+
+	var b strings.Builder
+	var open []string
+	skip := ""
+
+	writeText := func(text string) {
+		if skip != "" || text == "" {
+			return
+		}
+		if !slices.Contains(open, "pre") {
+			text = whitespace.ReplaceAllString(text, " ")
+			if len(open) > 0 && containerTags[open[len(open)-1]] && text == " " {
+				return
+			}
+		}
+		text = strings.ReplaceAll(text, "<", "&lt;")
+		text = strings.ReplaceAll(text, ">", "&gt;")
+		b.WriteString(text)
+	}
+	closeTo := func(i int) {
+		for len(open) > i {
+			b.WriteString("</" + open[len(open)-1] + ">")
+			open = open[:len(open)-1]
+		}
+	}
+
+	pos := 0
+	for _, m := range htmlTag.FindAllStringSubmatchIndex(s, -1) {
+		writeText(s[pos:m[0]])
+		pos = m[1]
+
+		closing := m[3] > m[2]
+		name := strings.ToLower(s[m[4]:m[5]])
+		if skip != "" {
+			if closing && name == skip {
+				skip = ""
+			}
+			continue
+		} else if droppedTags[name] {
+			if !closing {
+				skip = name
+			}
+			continue
+		}
+
+		attrs, ok := allowedTags[name]
+		if !ok {
+			continue
+		} else if closing {
+			if i := lastIndex(open, name); i >= 0 {
+				closeTo(i)
+			}
+			continue
+		}
+
+		switch name {
+		case "li":
+			if i := lastIndex(open, "li"); i >= 0 && !slices.ContainsFunc(open[i:], isList) {
+				closeTo(i)
+			}
+			if !slices.ContainsFunc(open, isList) {
+				b.WriteString("<ul>")
+				open = append(open, "ul")
+			}
+		case "p":
+			if i := lastIndex(open, "p"); i >= 0 {
+				closeTo(i)
+			}
+		}
+
+		b.WriteString("<" + name)
+		for _, a := range htmlAttr.FindAllStringSubmatch(s[m[6]:m[7]], -1) {
+			attr := strings.ToLower(a[1])
+			value := html.UnescapeString(a[2] + a[3] + a[4])
+			if !slices.Contains(attrs, attr) {
+				continue
+			} else if (attr == "href" || attr == "src") && !safeURL(value) {
+				continue
+			}
+			b.WriteString(" " + attr + `="` + html.EscapeString(value) + `"`)
+		}
+		b.WriteString(">")
+
+		if !voidTags[name] {
+			open = append(open, name)
+		}
+	}
+	writeText(s[pos:])
+	closeTo(0)
+
+	return escapeTemplate(strings.TrimSpace(b.String()))
+}
+
+func lastIndex(tags []string, tag string) int {
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i] == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+func isList(tag string) bool {
+	return tag == "ul" || tag == "ol"
+}
+
+// safeURL reports whether a link is relative or points to the web.
+func safeURL(url string) bool {
+	scheme, _, found := strings.Cut(url, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// escapeTemplate replaces what would end or interpolate a JavaScript template
+// literal by entities, which render the same once the HTML is parsed.
+func escapeTemplate(s string) string {
+	return strings.NewReplacer(
+		"`", "&#96;",
+		"${", "$&#123;",
+		`\`, "&#92;",
+	).Replace(s)
+}
+
+// unescapeTemplate reverts escapeTemplate for HTML which isn't put into the
+// card scripts.
+func unescapeTemplate(s string) string {
+	return strings.NewReplacer(
+		"&#96;", "`",
+		"$&#123;", "${",
+		"&#92;", `\`,
+	).Replace(s)
+}
+
+func unescapeTemplates(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	res := make([]string, len(s))
+	for i := range s {
+		res[i] = unescapeTemplate(s[i])
+	}
+	return res
+}
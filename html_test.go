@@ -0,0 +1,105 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`Open C:\Windows\System32`, `Open C:\Windows\System32`},
+		{`Share \\server\share`, `Share \\server\share`},
+		{`Ping \\fileserver01`, `Ping \\fileserver01`},
+		{`Match ^\d{3}-\w+\.txt$`, `Match ^\d{3}-\w+\.txt$`},
+		{`Match a digit with \\d`, `Match a digit with \\d`},
+		{`Match a backslash with ^\\\w$`, `Match a backslash with ^\\\w$`},
+		{`One<br />two<img src="a.png"/>`, `One<br>two<img src="a.png">`},
+	}
+	for _, tt := range tests {
+		if got := normalizeText(tt.text); got != tt.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestBackslashRoundTrip follows a text from the dump into a card: decoded
+// from the TextDB, sanitized and put into a template literal of the card
+// scripts, where the entities are parsed back into the text.
+func TestBackslashRoundTrip(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`"C:\\Windows\\System32"`, `C:\Windows\System32`},
+		{`"\\\\server\\share\\file.txt"`, `\\server\share\file.txt`},
+		{`"\\\\fileserver01"`, `\\fileserver01`},
+		{`"^[a-z]+\\d*\\.log$"`, `^[a-z]+\d*\.log$`},
+		{`"Escape the digit class as \\\\d"`, `Escape the digit class as \\d`},
+		{"\"Use `${HOME}\\\\bin`\"", "Use `${HOME}\\bin`"},
+	}
+	for _, tt := range tests {
+		textDB := testTextDB(t, nil)
+		decode(t, `{"k": `+tt.json+`}`, &textDB)
+
+		got := textDB.For("q1").HTML("$$k")
+		if strings.ContainsAny(got, "`\\") || strings.Contains(got, "${") {
+			t.Errorf("HTML of %s = %q, which breaks a template literal", tt.json, got)
+		}
+		if text := html.UnescapeString(got); text != tt.want {
+			t.Errorf("text of %s = %q, want %q", tt.json, text, tt.want)
+		}
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"plain  text\n here", "plain text here"},
+		{`<p style="color: red" class="x">Text</p>`, "<p>Text</p>"},
+		{`<b>bold <i>both</b> rest`, "<b>bold <i>both</i></b> rest"},
+		{`<p>one<p>two`, "<p>one</p><p>two</p>"},
+		{`<li>a<li>b`, "<ul><li>a</li><li>b</li></ul>"},
+		{"<ol start=\"3\">\n  <li>a</li>\n</ol>", `<ol start="3"><li>a</li></ol>`},
+		{`a<script>alert(1)</script>b<style>p{}</style>c`, "abc"},
+		{`<font color="red">x</font><!-- note -->`, "x"},
+		{`<a href="javascript:alert(1)" onclick="x">link</a>`, "<a>link</a>"},
+		{`<a href="https://learn.microsoft.com/a?b=1&amp;c=2">link</a>`, `<a href="https://learn.microsoft.com/a?b=1&amp;c=2">link</a>`},
+		{`<img src="a.png" alt="A" onerror="x">`, `<img src="a.png" alt="A">`},
+		{"<pre>  keep\n  this</pre>", "<pre>  keep\n  this</pre>"},
+		{"<table>\n<tr> <td>a</td> </tr>\n</table>", "<table><tr><td>a</td></tr></table>"},
+		{`1 < 2 > 0`, "1 &lt; 2 &gt; 0"},
+		{"`${x}` \\n", "&#96;$&#123;x}&#96; &#92;n"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHTML(tt.html); got != tt.want {
+			t.Errorf("sanitizeHTML(%q)\n got %q\nwant %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeTemplate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"`code`", "&#96;code&#96;"},
+		{"${x} and $y and {z}", "$&#123;x} and $y and {z}"},
+		{`C:\Temp`, `C:&#92;Temp`},
+		{"$${", "$$&#123;"},
+	}
+	for _, tt := range tests {
+		if got := escapeTemplate(tt.text); got != tt.want {
+			t.Errorf("escapeTemplate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if got := html.UnescapeString(escapeTemplate(tt.text)); got != tt.text {
+			t.Errorf("escapeTemplate(%q) renders as %q", tt.text, got)
+		}
+	}
+}
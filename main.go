@@ -1,13 +1,81 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"image/png"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// mediaFlags registers the flags for handling media and returns a function to
+// get the options for processing images after parsing.
+func mediaFlags(flags *flag.FlagSet, convertOpts *convertOptions) func() *MediaOptions {
+	var opts MediaOptions
+	var compression string
+
+	flags.BoolVar(&convertOpts.Placeholders, "media-placeholder", false,
+		"substitute missing images by a placeholder instead of failing")
+
+	optimize := flags.Bool("optimize-media", false,
+		"downscale and re-encode images, which also strips their metadata")
+	flags.IntVar(&opts.MaxWidth, "max-width", 1280,
+		"width wider images are downscaled to, 0 keeps the size")
+	flags.IntVar(&opts.JPEGQuality, "jpeg-quality", 85,
+		"quality of re-encoded JPEG images, 1-100")
+	flags.StringVar(&compression, "png-compression", "best",
+		"compression of re-encoded PNG images: default, speed, best or none")
+
+	return func() *MediaOptions {
+		if !*optimize {
+			return nil
+		}
+		switch compression {
+		case "speed":
+			opts.PNGCompression = png.BestSpeed
+		case "none":
+			opts.PNGCompression = png.NoCompression
+		case "default":
+			opts.PNGCompression = png.DefaultCompression
+		default:
+			opts.PNGCompression = png.BestCompression
+		}
+		return &opts
+	}
+}
+
+// splitList splits a comma-separated flag value.
+func splitList(s string) []string {
+	var res []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// missingTest lists the dumped tests to select from.
+func missingTest() error {
+	var b strings.Builder
+
+	entries, err := os.ReadDir(filepath.Join("out", "dump"))
+	if err != nil {
+		return err
+	}
+
+	b.WriteString("test is missing, select one:")
+	for _, entry := range entries {
+		if entry.IsDir() {
+			b.WriteRune('\n')
+			b.WriteString(strings.ToLower(entry.Name()))
+		}
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
 func run() error {
 	args := os.Args[1:]
 	if len(args) < 1 {
@@ -27,7 +95,17 @@ func run() error {
 		if len(args) >= 3 {
 			testName = args[2]
 		}
-		tests, err := dump(session, testName)
+
+		var languages string
+		flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+		flags.StringVar(&languages, "lang", "",
+			"comma-separated languages to fetch the texts in additionally, e.g. de,en")
+		if len(args) > 3 {
+			if err := flags.Parse(args[3:]); err != nil {
+				return err
+			}
+		}
+		tests, err := dump(session, testName, splitList(languages))
 		if err != nil {
 			return err
 		}
@@ -45,32 +123,80 @@ func run() error {
 		return nil
 	case "produce":
 		if len(args) < 2 {
-			var b strings.Builder
-
-			entries, err := os.ReadDir(filepath.Join("out", "dump"))
-			if err != nil {
-				return err
-			}
-
-			b.WriteString("test is missing, select one:")
-			for _, entry := range entries {
-				if entry.IsDir() {
-					b.WriteRune('\n')
-					b.WriteString(strings.ToLower(entry.Name()))
-				}
-			}
-			return fmt.Errorf("%s", b.String())
+			return missingTest()
 		}
+		testName := args[1]
 
+		var opts produceOptions
+		flags := flag.NewFlagSet("produce", flag.ContinueOnError)
+		flags.Float64Var(&opts.MinCoverage, "min-coverage", 0,
+			"fail if less than this ratio of questions could be converted")
+		flags.BoolVar(&opts.Strict, "strict", false,
+			"fail if any text is missing from the TextDB")
+		languages := flags.String("lang", "",
+			"comma-separated languages dumped with -lang to produce a deck for each")
+		flags.BoolVar(&opts.Bilingual, "bilingual", false,
+			"produce one deck in the first language with the second on the back")
+		flags.StringVar(&opts.Format, "format", "csv",
+			"format of the written files: csv, markdown, json, jsonl, gift, moodle or qti")
+		flags.BoolVar(&opts.PerQuestion, "per-question", false,
+			"write a Markdown file per question instead of per skill group")
+		media := mediaFlags(flags, &opts.convertOptions)
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		opts.Media = media()
+		opts.Languages = splitList(*languages)
+		return produce(testName, opts)
+	case "sync":
+		if len(args) < 2 {
+			return missingTest()
+		}
 		testName := args[1]
-		return produce(testName)
+
+		var opts syncOptions
+		flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+		flags.StringVar(&opts.URL, "url", "http://127.0.0.1:8765",
+			"address of AnkiConnect")
+		flags.StringVar(&opts.Deck, "deck", "",
+			"name of the deck, defaults to the test's name")
+		flags.BoolVar(&opts.DryRun, "dry-run", false,
+			"only report what would be changed")
+		flags.BoolVar(&opts.Strict, "strict", false,
+			"fail if any text is missing from the TextDB")
+		flags.StringVar(&opts.Language, "lang", "",
+			"language dumped with -lang to use the texts of")
+		media := mediaFlags(flags, &opts.convertOptions)
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		opts.Media = media()
+		return syncAnki(testName, opts)
+	case "validate":
+		if len(args) < 2 {
+			return missingTest()
+		}
+		problems, err := validate(args[1])
+		if err != nil {
+			return err
+		}
+		if err := printProblems(os.Stdout, problems); err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("found %d problems", len(problems))
+		}
+		return nil
+	case "templates":
+		return writeTemplates(filepath.Join("out", "templates"))
 	default:
-		return fmt.Errorf("first argument must be 'dump' or 'produce'")
+		return fmt.Errorf("first argument must be 'dump', 'produce', 'sync', 'validate' or 'templates'")
 	}
 }
 
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
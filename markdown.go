@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+	newlines      = regexp.MustCompile(`\n{2,}`)
+	trailingSpace = regexp.MustCompile(`[ \t]+\n`)
+	unsafeName    = regexp.MustCompile(`[\\/:*?"<>|#^\[\]]+`)
+)
+
+// markdownWriter converts the HTML of a card into Markdown as understood by
+// Obsidian. Tables are kept as HTML, which Markdown doesn't need to be
+// separated from the text around it.
+type markdownWriter struct {
+	b     strings.Builder
+	media string
+	lists []int
+	hrefs []string
+	pre   bool
+	table bool
+}
+
+func htmlAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, a := range htmlAttr.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3] + a[4])
+	}
+	return attrs
+}
+
+// mediaLink points relative links to the media folder.
+func (w *markdownWriter) mediaLink(link string) string {
+	if strings.Contains(link, ":") || link == "" {
+		return link
+	}
+	return w.media + "/" + link
+}
+
+func (w *markdownWriter) escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+		"<", `\<`, "$", `\$`, "==", `=\=`, "%%", `%\%`,
+	).Replace(s)
+}
+
+func (w *markdownWriter) newline() {
+	w.b.WriteString("\n")
+}
+
+func (w *markdownWriter) text(s string) {
+	s = html.UnescapeString(s)
+	switch {
+	case w.pre:
+		w.b.WriteString(s)
+	case w.table:
+		w.b.WriteString(html.EscapeString(whitespace.ReplaceAllString(s, " ")))
+	default:
+		w.b.WriteString(w.escape(whitespace.ReplaceAllString(s, " ")))
+	}
+}
+
+func (w *markdownWriter) tableTag(closing bool, name string, attrs map[string]string) {
+	switch name {
+	case "table", "tr", "th", "td", "b", "strong", "i", "em", "code":
+		if closing {
+			w.b.WriteString("</" + name + ">")
+		} else {
+			w.b.WriteString("<" + name + ">")
+		}
+	case "br":
+		w.b.WriteString("<br>")
+	case "img":
+		fmt.Fprintf(&w.b, `<img src="%s" alt="%s">`,
+			html.EscapeString(w.mediaLink(attrs["src"])),
+			html.EscapeString(attrs["alt"]),
+		)
+	}
+	if closing && name == "table" {
+		w.table = false
+		w.newline()
+	}
+}
+
+func (w *markdownWriter) tag(closing bool, name string, attrs map[string]string) {
+	if w.table {
+		w.tableTag(closing, name, attrs)
+		return
+	}
+
+	switch name {
+	case "p", "div", "blockquote", "details", "hr":
+		w.newline()
+		w.newline()
+	case "br":
+		w.newline()
+	case "h1", "h2", "h3", "h4", "h5", "h6", "summary":
+		if closing {
+			w.b.WriteString("**")
+			w.newline()
+		} else {
+			w.newline()
+			w.b.WriteString("**")
+		}
+	case "b", "strong":
+		w.b.WriteString("**")
+	case "i", "em":
+		w.b.WriteString("*")
+	case "code":
+		if !w.pre {
+			w.b.WriteString("`")
+		}
+	case "pre":
+		w.pre = !closing
+		w.b.WriteString("\n```\n")
+	case "ul", "ol":
+		if closing {
+			w.lists = w.lists[:max(0, len(w.lists)-1)]
+		} else {
+			n := -1
+			if name == "ol" {
+				n, _ = strconv.Atoi(attrs["start"])
+				n = max(n, 1) - 1
+			}
+			w.lists = append(w.lists, n)
+		}
+		w.newline()
+	case "li":
+		if closing || len(w.lists) == 0 {
+			break
+		}
+		w.newline()
+		w.b.WriteString(strings.Repeat("  ", len(w.lists)-1))
+		if n := &w.lists[len(w.lists)-1]; *n < 0 {
+			w.b.WriteString("- ")
+		} else {
+			*n++
+			fmt.Fprintf(&w.b, "%d. ", *n)
+		}
+	case "a":
+		if !closing {
+			w.hrefs = append(w.hrefs, w.mediaLink(attrs["href"]))
+			w.b.WriteString("[")
+		} else if len(w.hrefs) > 0 {
+			fmt.Fprintf(&w.b, "](<%s>)", w.hrefs[len(w.hrefs)-1])
+			w.hrefs = w.hrefs[:len(w.hrefs)-1]
+		}
+	case "img":
+		fmt.Fprintf(&w.b, "![%s](<%s>)", w.escape(attrs["alt"]), w.mediaLink(attrs["src"]))
+	case "table":
+		w.newline()
+		w.table = true
+		w.b.WriteString("<table>")
+	}
+}
+
+// htmlToMarkdown converts the HTML of a card into Markdown, linking its
+// images relative to the given media folder.
+func htmlToMarkdown(s string, media string) string {
+	w := &markdownWriter{media: media}
+
+	s = htmlComment.ReplaceAllString(s, "")
+	pos := 0
+	for _, m := range htmlTag.FindAllStringSubmatchIndex(s, -1) {
+		w.text(s[pos:m[0]])
+		pos = m[1]
+		w.tag(m[3] > m[2], strings.ToLower(s[m[4]:m[5]]), htmlAttrs(s[m[6]:m[7]]))
+	}
+	w.text(s[pos:])
+
+	md := trailingSpace.ReplaceAllString(w.b.String(), "\n")
+	md = blankLines.ReplaceAllString(md, "\n\n")
+	return strings.TrimSpace(md)
+}
+
+// withoutBlankLines joins the paragraphs of a card, as blank lines end a
+// multi-line card of Obsidian's Spaced Repetition plugin.
+func withoutBlankLines(s string) string {
+	return newlines.ReplaceAllString(s, "\n")
+}
+
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
+
+// obsidianTags returns the tags of a record, nested by slashes instead of
+// colons, along with the deck of the Spaced Repetition plugin.
+func obsidianTags(testName string, record Record) []string {
+	tags := []string{"flashcards/" + strings.ToLower(testName)}
+	for _, tag := range record.Tags() {
+		tags = append(tags, strings.ReplaceAll(tag, "::", "/"))
+	}
+	return tags
+}
+
+func writeFrontMatter(b *strings.Builder, fields [][2]string, tags []string) {
+	b.WriteString("---\n")
+	for _, f := range fields {
+		fmt.Fprintf(b, "%s: %s\n", f[0], f[1])
+	}
+	b.WriteString("tags:\n")
+	for _, tag := range tags {
+		fmt.Fprintf(b, "  - %s\n", yamlString(tag))
+	}
+	b.WriteString("---\n")
+}
+
+// writeCard writes a record as a multi-line card, the question and the answer
+// being separated by a line with a single question mark.
+func writeCard(b *strings.Builder, record Record, media string) {
+	parts := partsOf(record)
+
+	question := []string{
+		htmlToMarkdown(parts.Text, media),
+		htmlToMarkdown(parts.Exhibits.HTML(), media),
+		htmlToMarkdown(parts.Front, media),
+	}
+	answer := []string{
+		htmlToMarkdown(parts.Back, media),
+		htmlToMarkdown(parts.Explanation, media),
+		htmlToMarkdown(parts.Context.ReferencesHTML(), media),
+	}
+
+	join := func(parts []string) string {
+		var res []string
+		for _, p := range parts {
+			if p != "" {
+				res = append(res, withoutBlankLines(p))
+			}
+		}
+		return strings.Join(res, "\n")
+	}
+
+	b.WriteString(join(question))
+	b.WriteString("\n?\n")
+	b.WriteString(join(answer))
+	b.WriteString("\n")
+}
+
+func markdownFileName(name string) string {
+	return strings.TrimSpace(unsafeName.ReplaceAllString(name, "-")) + ".md"
+}
+
+// writeMarkdown writes the records into a folder of Markdown files, one per
+// skill group or per question if perQuestion is set. The media is expected in
+// the media folder next to them.
+func writeMarkdown(dir string, testName string, records []Record, perQuestion bool) error {
+	files := make(map[string]*strings.Builder)
+	var order []string
+
+	for _, record := range records {
+		parts := partsOf(record)
+
+		name := markdownFileName(parts.Group.Name)
+		if perQuestion {
+			name = markdownFileName(parts.ID)
+		}
+
+		b, ok := files[name]
+		if !ok {
+			b = &strings.Builder{}
+			files[name] = b
+			order = append(order, name)
+
+			fields := [][2]string{
+				{"test", yamlString(testName)},
+				{"skill_group", yamlString(parts.Group.Name)},
+			}
+			if perQuestion {
+				fields = append([][2]string{
+					{"id", yamlString(parts.ID)},
+					{"type", parts.Kind},
+				}, fields...)
+			}
+			tags := []string{"flashcards/" + strings.ToLower(testName)}
+			if perQuestion {
+				tags = obsidianTags(testName, record)
+			}
+			writeFrontMatter(b, fields, tags)
+		}
+
+		if !perQuestion {
+			// What the front matter holds for a file per question, as inline
+			// fields of Dataview.
+			fmt.Fprintf(b, "\n## %s (%s)\n\n", parts.ID, parts.Kind)
+			fmt.Fprintf(b, "id:: %s\ntype:: %s\n", parts.ID, parts.Kind)
+		}
+		b.WriteString("\n")
+		writeCard(b, record, "media")
+	}
+
+	for _, name := range order {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(files[name].String()), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
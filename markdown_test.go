@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"<p>One</p><p>Two <b>bold</b> <i>it</i></p>", "One\n\nTwo **bold** *it*"},
+		{"a<br>b", "a\nb"},
+		{"<h3>Title</h3>text", "**Title**\ntext"},
+		{"<ul><li>a</li><li>b<ol start=\"3\"><li>c</li><li>d</li></ol></li></ul>", "- a\n- b\n\n  3. c\n  4. d"},
+		{`<a href="https://a.com/x y">Docs</a>`, "[Docs](<https://a.com/x y>)"},
+		{`<img src="q1.png" alt="A [b]">`, `![A \[b\]](<media/q1.png>)`},
+		{"<code>a*b</code>", "`a\\*b`"},
+		{"<pre><code>if a &lt; b {\n  x_y()\n}</code></pre>", "```\nif a < b {\n  x_y()\n}\n```"},
+		{"<table><tr><td>a &amp; b</td><td><img src=\"t.png\" alt=\"T\"></td></tr></table>",
+			`<table><tr><td>a &amp; b</td><td><img src="media/t.png" alt="T"></td></tr></table>`},
+		{"1 &lt; 2, $5 and C:&#92;Temp ==x== %%", `1 \< 2, \$5 and C:\\Temp =\=x=\= %\%`},
+		{"<!-- hidden -->shown", "shown"},
+	}
+	for _, tt := range tests {
+		if got := htmlToMarkdown(tt.html, "media"); got != tt.want {
+			t.Errorf("htmlToMarkdown(%q)\n got %q\nwant %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	group := SkillGroup{ID: 1, Name: "Group: One"}
+	records := []Record{
+		&SingleChoice{
+			ID: "1", Group: group, Text: "<p>Which one?</p><p>Pick.</p>",
+			Options: []string{"A", "B"}, Answer: 2, Explanation: "Because.",
+		},
+		&SingleChoice{
+			ID: "2", Group: group, Text: "Second?",
+			Options: []string{"C", "D"}, Answer: 1, Explanation: "So.",
+		},
+	}
+	records[1].SetCaseStudy(&CaseStudy{ID: "cs1"})
+
+	dir := t.TempDir()
+	if err := writeMarkdown(dir, "T1", records, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "Group- One.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+test: "T1"
+skill_group: "Group: One"
+tags:
+  - "flashcards/t1"
+---
+
+## 1 (singleChoice)
+
+id:: 1
+type:: singleChoice
+
+Which one?
+Pick.
+- A
+- B
+?
+- B
+Because.
+
+## 2 (singleChoice)
+
+id:: 2
+type:: singleChoice
+
+Second?
+- C
+- D
+?
+- C
+So.
+`
+	if string(got) != want {
+		t.Errorf("file per skill group:\n%s\nwant\n%s", got, want)
+	}
+
+	dir = t.TempDir()
+	if err := writeMarkdown(dir, "T1", records, true); err != nil {
+		t.Fatal(err)
+	}
+	got, err = os.ReadFile(filepath.Join(dir, "2.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `---
+id: "2"
+type: singleChoice
+test: "T1"
+skill_group: "Group: One"
+tags:
+  - "flashcards/t1"
+  - "casestudy/cs1"
+---
+
+Second?
+- C
+- D
+?
+- C
+So.
+`
+	if string(got) != want {
+		t.Errorf("file per question:\n%s\nwant\n%s", got, want)
+	}
+}
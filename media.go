@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MediaStore keeps media files named by the hash of their content, so that an
+// image shared by many questions is stored only once.
+type MediaStore struct {
+	dir   string
+	index string
+	// Paths maps the original MeasureUp path to the stored file name.
+	Paths map[string]string
+	// Process enables processing images while copying them.
+	Process *MediaOptions
+
+	originals map[string]*MediaOriginal
+	processed map[string]bool
+}
+
+// OpenMediaStore opens the store of a dump, which keeps its files in
+// images/ and the path mapping in media.json.
+func OpenMediaStore(dumpDir string) (*MediaStore, error) {
+	ms := &MediaStore{
+		dir:   filepath.Join(dumpDir, "images"),
+		index: filepath.Join(dumpDir, "media.json"),
+		Paths: make(map[string]string),
+
+		originals: make(map[string]*MediaOriginal),
+		processed: make(map[string]bool),
+	}
+
+	buf, err := os.ReadFile(ms.index)
+	if os.IsNotExist(err) {
+		return ms, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return ms, json.Unmarshal(buf, &ms.Paths)
+}
+
+func hashedName(name string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]) + strings.ToLower(filepath.Ext(name))
+}
+
+func (ms *MediaStore) Lookup(path string) (string, bool) {
+	name, ok := ms.Paths[path]
+	return name, ok
+}
+
+// File returns the path of the file stored for the given MeasureUp path.
+func (ms *MediaStore) File(questionName string, path string) string {
+	if name, ok := ms.Lookup(path); ok {
+		return filepath.Join(ms.dir, name)
+	}
+	parts := strings.Split(path, "/")
+	return filepath.Join(ms.dir, questionName+"-"+parts[len(parts)-1])
+}
+
+// Put stores the content of the file at path unless an identical one exists
+// and returns its name.
+func (ms *MediaStore) Put(path string, data []byte) (string, error) {
+	name := hashedName(path, data)
+	dest := filepath.Join(ms.dir, name)
+
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	ms.Paths[path] = name
+	return name, ms.save()
+}
+
+func (ms *MediaStore) save() error {
+	buf, err := json.MarshalIndent(ms.Paths, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ms.index, buf, 0o644)
+}
+
+// Copy copies the file stored for path into the folder to and returns its
+// name there. Dumps made before the store existed named their files after the
+// question, those are hashed while copying.
+func (ms *MediaStore) Copy(questionName string, path string, to string) (string, error) {
+	name, ok := ms.Lookup(path)
+	src := ms.File(questionName, path)
+
+	buf, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	} else if len(buf) == 0 {
+		return "", fmt.Errorf("%s is empty", src)
+	}
+	if !ok {
+		name = hashedName(path, buf)
+	}
+
+	if ms.Process != nil {
+		if !ms.processed[name] {
+			orig, err := processImage(buf, name, to, *ms.Process)
+			if err != nil {
+				return "", fmt.Errorf("processing %s: %v", src, err)
+			} else if orig != nil {
+				orig.source = src
+			}
+			ms.originals[name] = orig
+			ms.processed[name] = true
+		}
+		return name, nil
+	}
+
+	dest := filepath.Join(to, name)
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		if err := os.WriteFile(dest, buf, 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	return name, nil
+}
+
+// MissingMedia is an image of a question which couldn't be copied.
+type MissingMedia struct {
+	Question string
+	Path     string
+	Alt      string
+	Error    string
+}
+
+// mediaCopier copies the media of questions and keeps track of the ones
+// which are missing, substituting them by a placeholder if enabled.
+type mediaCopier struct {
+	store        *MediaStore
+	to           string
+	placeholders bool
+	missing      []MissingMedia
+}
+
+func (mc *mediaCopier) Copy(questionName string, image *QuestionImage) {
+	name, err := mc.store.Copy(questionName, image.Name, mc.to)
+	if err == nil {
+		image.Name = name
+		image.Original = mc.store.Original(name)
+		return
+	}
+
+	mc.missing = append(mc.missing, MissingMedia{
+		Question: questionName,
+		Path:     image.Name,
+		Alt:      image.Alt,
+		Error:    err.Error(),
+	})
+
+	image.Original = nil
+	if mc.placeholders {
+		name, err := writePlaceholder(mc.to, image.Name, image.Alt)
+		if err == nil {
+			image.Name = name
+		}
+	}
+}
+
+// writePlaceholder writes an SVG image stating which image is missing and
+// returns its name.
+func writePlaceholder(to string, path string, alt string) (string, error) {
+	text := alt
+	if text == "" {
+		text = path
+	}
+	if r := []rune(text); len(r) > 60 {
+		text = string(r[:59]) + "…"
+	}
+
+	svg := fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="480" height="120">`+
+			`<rect width="480" height="120" fill="#eee" stroke="#c00" stroke-width="4" stroke-dasharray="12 8"/>`+
+			`<text x="240" y="50" text-anchor="middle" font-family="sans-serif" font-size="16" fill="#c00">Missing image</text>`+
+			`<text x="240" y="80" text-anchor="middle" font-family="sans-serif" font-size="14" fill="#333">%s</text>`+
+			`</svg>`,
+		html.EscapeString(text),
+	)
+
+	name := "missing-" + hashedName(".svg", []byte(path+"\x00"+alt))
+	return name, os.WriteFile(filepath.Join(to, name), []byte(svg), 0o644)
+}
+
+// MediaOriginal describes the original of a downscaled image.
+type MediaOriginal struct {
+	Name        string
+	Width       int
+	Height      int
+	ScaledWidth int
+
+	source string
+}
+
+// MediaOptions configures how images are processed when copied into the media
+// folder.
+type MediaOptions struct {
+	// MaxWidth is the width wider images are downscaled to, 0 disables it.
+	MaxWidth       int
+	JPEGQuality    int
+	PNGCompression png.CompressionLevel
+}
+
+func (ms *MediaStore) Original(name string) *MediaOriginal {
+	return ms.originals[name]
+}
+
+// WriteOriginals copies the originals of downscaled images into the folder
+// to, but only the ones among linked as the others would never be shown.
+func (ms *MediaStore) WriteOriginals(to string, linked []string) error {
+	for _, orig := range ms.originals {
+		if orig == nil || !slices.Contains(linked, orig.Name) {
+			continue
+		}
+
+		dest := filepath.Join(to, orig.Name)
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		buf, err := os.ReadFile(orig.source)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, buf, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processImage re-encodes a PNG or JPEG image, which drops any metadata, and
+// downscales it if it's too wide. The original is then described by the
+// returned value, it's written by WriteOriginals once it's known to be linked.
+func processImage(buf []byte, name string, to string, opts MediaOptions) (*MediaOriginal, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+		return nil, os.WriteFile(filepath.Join(to, name), buf, 0o644)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	var orig *MediaOriginal
+	if b := img.Bounds(); opts.MaxWidth > 0 && b.Dx() > opts.MaxWidth {
+		orig = &MediaOriginal{
+			Name:        strings.TrimSuffix(name, filepath.Ext(name)) + "-full" + ext,
+			Width:       b.Dx(),
+			Height:      b.Dy(),
+			ScaledWidth: opts.MaxWidth,
+		}
+		img = downscale(img, opts.MaxWidth)
+	}
+
+	f, err := os.Create(filepath.Join(to, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if ext == ".png" {
+		enc := png.Encoder{CompressionLevel: opts.PNGCompression}
+		err = enc.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: opts.JPEGQuality})
+	}
+	return orig, err
+}
+
+// downscale resizes img to the given width by averaging the pixels each
+// target pixel covers.
+func downscale(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	out := image.NewRGBA64(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/height
+		sy1 := b.Min.Y + (y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			sx0 := b.Min.X + x*b.Dx()/width
+			sx1 := b.Min.X + (x+1)*b.Dx()/width
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			out.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return out
+}
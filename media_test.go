@@ -0,0 +1,193 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestStore(t *testing.T) (*MediaStore, string) {
+	t.Helper()
+	dump := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dump, "images"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenMediaStore(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dump
+}
+
+func TestMediaStoreDeduplicates(t *testing.T) {
+	store, dump := openTestStore(t)
+	data := testPNG(t, 2, 2)
+
+	a, err := store.Put("img/q1/a.png", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.Put("img/q2/b.PNG", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("identical images got different names %s and %s", a, b)
+	}
+	if files, _ := os.ReadDir(filepath.Join(dump, "images")); len(files) != 1 {
+		t.Errorf("got %d stored files, want 1", len(files))
+	}
+
+	reopened, err := OpenMediaStore(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := reopened.Lookup("img/q2/b.PNG"); !ok || name != a {
+		t.Errorf("lookup after reopening = %s, %v, want %s", name, ok, a)
+	}
+}
+
+func TestMediaStoreCopy(t *testing.T) {
+	store, dump := openTestStore(t)
+	to := t.TempDir()
+	data := testPNG(t, 2, 2)
+
+	stored, err := store.Put("img/q1/a.png", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := store.Copy("Q_1", "img/q1/a.png", to)
+	if err != nil || name != stored {
+		t.Fatalf("copy = %s, %v, want %s", name, err, stored)
+	}
+
+	// Dumps made before the store named their files after the question.
+	os.WriteFile(filepath.Join(dump, "images", "Q_2-old.png"), data, 0o644)
+	name, err = store.Copy("Q_2", "img/q2/old.png", to)
+	if err != nil || name != hashedName("old.png", data) {
+		t.Fatalf("copy of a legacy file = %s, %v", name, err)
+	}
+	if _, err := os.Stat(filepath.Join(to, name)); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := store.Copy("Q_3", "img/q3/missing.png", to); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	os.WriteFile(filepath.Join(dump, "images", "Q_4-empty.png"), nil, 0o644)
+	if _, err := store.Copy("Q_4", "img/q4/empty.png", to); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	to := t.TempDir()
+	opts := MediaOptions{MaxWidth: 4, JPEGQuality: 80}
+
+	orig, err := processImage(testPNG(t, 8, 6), "wide.png", to, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orig == nil || orig.Name != "wide-full.png" || orig.Width != 8 || orig.Height != 6 || orig.ScaledWidth != 4 {
+		t.Fatalf("original = %+v, want the size of the wide image", orig)
+	}
+	f, err := os.Open(filepath.Join(to, "wide.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width != 4 || cfg.Height != 3 {
+		t.Errorf("downscaled image is %dx%d (%v), want 4x3", cfg.Width, cfg.Height, err)
+	}
+
+	if orig, err := processImage(testPNG(t, 2, 2), "narrow.png", to, opts); err != nil || orig != nil {
+		t.Errorf("narrow image = %v, %v, want no original", orig, err)
+	}
+	if _, err := processImage([]byte("corrupt"), "corrupt.png", to, opts); err == nil {
+		t.Error("expected an error for a corrupt image")
+	}
+}
+
+func TestConvertWritesLinkedOriginals(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"skillGroups.json": `[{"ID": 1, "Name": "Group One", "Questions": [
+			{"Name": "MUP/Q_1", "question_type": "singleChoice"},
+			{"Name": "MUP/Q_2", "question_type": "unsupported"}
+		]}]`,
+		"questions/Q_2.json": `{
+			"Exhibit": {"Content": [{"image": "img/q2/other.png"}]},
+			"StartSlide": {"Value": "views/Q_2_s"}
+		}`,
+		"slides/Q_2_s.json":    `{}`,
+		"images/Q_1-ex.png":    string(testPNG(t, 8, 4)),
+		"images/Q_2-other.png": string(testPNG(t, 8, 2)),
+	})
+
+	opts := convertOptions{Media: &MediaOptions{MaxWidth: 4}}
+	records, _, err := convert("t1", "out/collection.media", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linked := records[0].(*SingleChoice).Exhibits[0].Image.Original
+	if linked == nil {
+		t.Fatal("the exhibit wasn't downscaled")
+	}
+	files, _ := filepath.Glob("out/collection.media/*-full.png")
+	if len(files) != 1 || filepath.Base(files[0]) != linked.Name {
+		t.Errorf("originals = %v, want only %s", files, linked.Name)
+	}
+}
+
+func TestCopyReportsCorruptImages(t *testing.T) {
+	store, _ := openTestStore(t)
+	store.Process = &MediaOptions{MaxWidth: 4}
+
+	if _, err := store.Put("img/q1/a.png", []byte("corrupt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Copy("Q_1", "img/q1/a.png", t.TempDir()); err == nil {
+		t.Error("expected an error for a corrupt image")
+	}
+}
+
+func TestMediaCopierMissing(t *testing.T) {
+	store, _ := openTestStore(t)
+	to := t.TempDir()
+
+	copier := &mediaCopier{store: store, to: to}
+	image := QuestionImage{Name: "img/q1/gone.png", Alt: "Network diagram"}
+	copier.Copy("Q_1", &image)
+	if len(copier.missing) != 1 || copier.missing[0].Path != "img/q1/gone.png" || copier.missing[0].Question != "Q_1" {
+		t.Errorf("missing = %+v, want the image of Q_1", copier.missing)
+	}
+
+	copier = &mediaCopier{store: store, to: to, placeholders: true}
+	image = QuestionImage{Name: "img/q1/gone.png", Alt: "Network diagram"}
+	copier.Copy("Q_1", &image)
+	if filepath.Ext(image.Name) != ".svg" {
+		t.Fatalf("image = %s, want a placeholder", image.Name)
+	}
+	svg, err := os.ReadFile(filepath.Join(to, image.Name))
+	if err != nil || !strings.Contains(string(svg), "Network diagram") {
+		t.Errorf("placeholder = %s, %v, want the alt text", svg, err)
+	}
+}
+
+func TestConvertReportsMissingMedia(t *testing.T) {
+	writeTestDump(t, map[string]string{"images/Q_1-ex.png": ""})
+
+	records, report, err := convert("t1", "out/collection.media", convertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(report.MissingMedia) != 1 {
+		t.Errorf("got %d records and missing media %v, want the question with its image missing", len(records), report.MissingMedia)
+	}
+	if files, _ := os.ReadDir("out/collection.media"); len(files) != 0 {
+		t.Errorf("media folder has %d files, want none", len(files))
+	}
+}
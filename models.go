@@ -3,6 +3,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"html"
+	"image"
+	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -17,7 +21,7 @@ func CSVColumns() []string {
 	for i := 1; i <= MaxOptions; i++ {
 		cols = append(cols, "Option-"+strconv.Itoa(i))
 	}
-	cols = append(cols, "Type", "Image", "Answer")
+	cols = append(cols, "Type", "Image", "Answer", "CaseStudy", "Translation", "References")
 	return cols
 }
 
@@ -46,12 +50,6 @@ type SkillGroupQuestion struct {
 	Stem string
 }
 
-type TextDB map[string]string
-
-func (db TextDB) Get(key string) string {
-	return db[strings.TrimPrefix(key, "$$")]
-}
-
 type Question struct {
 	Stem struct {
 		Value string
@@ -65,6 +63,9 @@ type Question struct {
 	StartSlide struct {
 		Value string
 	}
+	Slides []struct {
+		Value string
+	}
 	Exhibit struct {
 		Content interface{}
 	}
@@ -78,22 +79,24 @@ type Question struct {
 	}
 }
 
-func (q Question) Images() QuestionImages {
-	var res QuestionImages
-
-	switch content := q.Exhibit.Content.(type) {
-	case []interface{}:
-		for _, c := range content {
-			c := c.(map[string]interface{})
-			image := c["image"].(string)
-			alt := c["alt"].(string)
-			res = append(res, QuestionImage{
-				Name: image,
-				Alt:  alt,
-			})
+// SlideNames returns the start slide followed by all further slides the
+// question refers to.
+func (q Question) SlideNames() []string {
+	names := []string{q.StartSlide.Value}
+	for _, s := range q.Slides {
+		if s.Value != "" && !slices.Contains(names, s.Value) {
+			names = append(names, s.Value)
 		}
 	}
-	return res
+	return names
+}
+
+func (q Question) Exhibits() Exhibits {
+	return parseExhibits(q.Exhibit.Content)
+}
+
+func (q Question) Images() QuestionImages {
+	return q.Exhibits().Images()
 }
 
 func (q Question) Correct() [][]string {
@@ -154,6 +157,31 @@ func (q Question) Statements() [][]string {
 type QuestionImage struct {
 	Name string
 	Alt  string
+	// Original is set if the image was downscaled.
+	Original *MediaOriginal `json:"-"`
+}
+
+func (qi QuestionImage) HTML(class string) string {
+	if qi.Name == "" {
+		return ""
+	}
+
+	img := fmt.Sprintf(
+		`<img src="%s" alt="%s" class="%s">`,
+		qi.Name,
+		escapeTemplate(html.EscapeString(qi.Alt)),
+		class,
+	)
+	if qi.Original == nil {
+		return img
+	}
+	return fmt.Sprintf(
+		`<a href="%s" class="full-size" data-width="%d" data-height="%d">%s</a>`,
+		qi.Original.Name,
+		qi.Original.Width,
+		qi.Original.Height,
+		img,
+	)
 }
 
 type QuestionImages []QuestionImage
@@ -161,20 +189,17 @@ type QuestionImages []QuestionImage
 func (qi QuestionImages) HTML() string {
 	var images []string
 	for _, image := range qi {
-		images = append(images, fmt.Sprintf(
-			`<img src="%s" alt="%s" class="exhibit">`,
-			image.Name,
-			strings.ReplaceAll(image.Alt, `"`, `&quot;`),
-		))
+		images = append(images, image.HTML("exhibit"))
 	}
 	return strings.Join(images, "\n<br>\n")
 }
 
 type QuestionSlide struct {
 	View struct {
-		ID    string
-		Image string
-		Alt   string
+		ID       string
+		Image    string
+		Alt      string
+		Original *MediaOriginal `json:"-"`
 	}
 	RadioButtons []struct {
 		ID    string
@@ -191,31 +216,145 @@ type QuestionSlide struct {
 	SelectPlaceMup []struct {
 		ID      string
 		Options []struct {
+			ID  string
 			Alt string
 		}
 	}
+	DragSources []struct {
+		ID    string
+		Value string
+	}
+	DragTargets []struct {
+		ID    string
+		Value string
+	}
+	HotSpots []struct {
+		ID     string
+		X      float64
+		Y      float64
+		Width  float64
+		Height float64
+	}
 	Images []struct {
-		Image string
-		Alt   string
+		Image    string
+		Alt      string
+		Original *MediaOriginal `json:"-"`
+	}
+	Texts []struct {
+		ID    string
+		Value string
 	}
 	CaseStudy []struct {
-		Options []struct {
-			Label     string
-			CSContext string
+		Options []CaseStudyOption
+	}
+}
+
+func (s QuestionSlide) ViewImage() QuestionImage {
+	return QuestionImage{
+		Name:     s.View.Image,
+		Alt:      s.View.Alt,
+		Original: s.View.Original,
+	}
+}
+
+// AllImages returns the view's image followed by the other images of the
+// slide.
+func (s QuestionSlide) AllImages() QuestionImages {
+	var res QuestionImages
+	if s.View.Image != "" {
+		res = append(res, s.ViewImage())
+	}
+	for _, image := range s.Images {
+		res = append(res, QuestionImage{
+			Name:     image.Image,
+			Alt:      image.Alt,
+			Original: image.Original,
+		})
+	}
+	return res
+}
+
+type QuestionSlides []QuestionSlide
+
+// Merged combines the slides of a question into one, using the view of the
+// first one.
+func (qs QuestionSlides) Merged() QuestionSlide {
+	var res QuestionSlide
+	for i, s := range qs {
+		if i == 0 {
+			res.View = s.View
+		}
+		res.RadioButtons = append(res.RadioButtons, s.RadioButtons...)
+		res.CheckBoxes = append(res.CheckBoxes, s.CheckBoxes...)
+		res.Selects = append(res.Selects, s.Selects...)
+		res.SelectPlaceMup = append(res.SelectPlaceMup, s.SelectPlaceMup...)
+		res.DragSources = append(res.DragSources, s.DragSources...)
+		res.DragTargets = append(res.DragTargets, s.DragTargets...)
+		res.HotSpots = append(res.HotSpots, s.HotSpots...)
+		res.Images = append(res.Images, s.Images...)
+		res.Texts = append(res.Texts, s.Texts...)
+		res.CaseStudy = append(res.CaseStudy, s.CaseStudy...)
+	}
+	return res
+}
+
+type CaseStudyOption struct {
+	Label     string
+	CSContext string
+	View      string
+	Options   []CaseStudyOption
+}
+
+// CaseStudyOptions flattens the options of all case-study blocks including
+// nested ones. Options referring to the same context are only returned once.
+func (s QuestionSlide) CaseStudyOptions() ([]CaseStudyOption, error) {
+	if len(s.CaseStudy) == 0 {
+		return nil, fmt.Errorf("case study has no option blocks")
+	}
+
+	var res []CaseStudyOption
+	var contexts []string
+
+	var walk func(opts []CaseStudyOption)
+	walk = func(opts []CaseStudyOption) {
+		for _, opt := range opts {
+			if opt.CSContext != "" && slices.Contains(contexts, opt.CSContext) {
+				continue
+			} else if opt.CSContext != "" {
+				contexts = append(contexts, opt.CSContext)
+			}
+			if opt.CSContext != "" || opt.View != "" {
+				res = append(res, opt)
+			}
+			walk(opt.Options)
 		}
 	}
+	for _, block := range s.CaseStudy {
+		walk(block.Options)
+	}
+
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("case study has no questions")
+	}
+	return res, nil
 }
 
 type Record interface {
+	NoteType() NoteType
 	Record() []string
+	Tags() []string
+	SetCaseStudy(cs *CaseStudy)
+	SetTranslation(html string)
+	References() References
 }
 
 type SingleChoice struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
+	Exhibits    Exhibits
 	Options     []string
 	Answer      int
 }
@@ -225,34 +364,43 @@ func NewSingleChoice(
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
+	exhibits Exhibits,
+	slides QuestionSlides,
 ) *SingleChoice {
+	slide := slides.Merged()
+
 	var options []string
-	for _, btn := range slide.RadioButtons {
-		options = append(options, textDB.Get(btn.Value))
+	for i, btn := range slide.RadioButtons {
+		options = append(options, textDB.In(fmt.Sprintf("RadioButtons[%d].Value", i)).HTML(btn.Value))
 	}
 
 	var answer int
 	correctBtn := question.Correct()[0][0]
-	for _, btn := range slide.RadioButtons {
+	for i, btn := range slide.RadioButtons {
 		if btn.ID == correctBtn {
-			answer = slices.Index(options, textDB.Get(btn.Value)) + 1
+			answer = slices.Index(options, options[i]) + 1
 			break
 		}
 	}
 
+	rc, explanation := newRecordContext(textDB, question)
+
 	return &SingleChoice{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		Options:     options,
-		Answer:      answer,
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Options:       options,
+		Answer:        answer,
 	}
 }
 
+func (sc *SingleChoice) NoteType() NoteType {
+	return MeasureUpCard
+}
+
 func (sc *SingleChoice) Record() []string {
 	record := append([]string{},
 		sc.ID, sc.Text, sc.Explanation, sc.Exhibits.HTML())
@@ -266,17 +414,19 @@ func (sc *SingleChoice) Record() []string {
 	}
 
 	record = append(record, options...)
-	record = append(record, "singleChoice", "", strconv.Itoa(sc.Answer))
+	record = append(record, "singleChoice", "", strconv.Itoa(sc.Answer),
+		sc.CaseStudyHTML(), sc.Translation, sc.ReferencesHTML())
 
 	return record
 }
 
 type MultipleChoice struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
+	Exhibits    Exhibits
 	Options     []string
 	Answers     []int
 }
@@ -286,35 +436,44 @@ func NewMultipleChoice(
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
+	exhibits Exhibits,
+	slides QuestionSlides,
 ) *MultipleChoice {
+	slide := slides.Merged()
+
 	var options []string
-	for _, btn := range slide.CheckBoxes {
-		options = append(options, textDB.Get(btn.Value))
+	for i, btn := range slide.CheckBoxes {
+		options = append(options, textDB.In(fmt.Sprintf("CheckBoxes[%d].Value", i)).HTML(btn.Value))
 	}
 
 	var answers []int
 	correctBtns := question.Correct()[0]
-	for _, btn := range slide.CheckBoxes {
+	for i, btn := range slide.CheckBoxes {
 		if slices.Index(correctBtns, btn.ID) > -1 {
-			idx := slices.Index(options, textDB.Get(btn.Value))
+			idx := slices.Index(options, options[i])
 			answers = append(answers, idx+1)
 		}
 	}
 	slices.Sort(answers)
 
+	rc, explanation := newRecordContext(textDB, question)
+
 	return &MultipleChoice{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		Options:     options,
-		Answers:     answers,
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Options:       options,
+		Answers:       answers,
 	}
 }
 
+func (mc *MultipleChoice) NoteType() NoteType {
+	return MeasureUpCard
+}
+
 func (mc *MultipleChoice) Record() []string {
 	record := append([]string{},
 		mc.ID, mc.Text, mc.Explanation, mc.Exhibits.HTML())
@@ -329,19 +488,25 @@ func (mc *MultipleChoice) Record() []string {
 
 	record = append(record, options...)
 	answers, _ := json.Marshal(mc.Answers)
-	record = append(record, "multipleChoice", "", string(answers))
+	record = append(record, "multipleChoice", "", string(answers),
+		mc.CaseStudyHTML(), mc.Translation, mc.ReferencesHTML())
 
 	return record
 }
 
+type LiveScreenScreen struct {
+	Image     QuestionImage
+	Dropdowns int
+}
+
 type LiveScreen struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
-	ImageName   string
-	ImageAlt    string
+	Exhibits    Exhibits
+	Screens     []LiveScreenScreen
 	Options     [][]string
 	Answers     []int
 }
@@ -351,78 +516,144 @@ func NewLiveScreen(
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
+	exhibits Exhibits,
+	slides QuestionSlides,
 ) *LiveScreen {
+	correct := question.Correct()
+
+	var screens []LiveScreenScreen
 	var options [][]string
-	for _, sel := range slide.Selects {
-		for _, m := range question.Models {
-			if m.Model == sel.Model {
+	var answers []int
+	for _, slide := range slides {
+		screen := LiveScreenScreen{
+			Image: slide.ViewImage(),
+		}
+		for _, sel := range slide.Selects {
+			for i, m := range question.Models {
+				if m.Model != sel.Model {
+					continue
+				}
+
 				var opts []string
-				for _, opt := range m.Options.([]interface{}) {
-					opts = append(opts, textDB.Get(opt.(string)))
+				for j, opt := range m.Options.([]interface{}) {
+					field := fmt.Sprintf("Models[%d].Options[%d]", i, j)
+					opts = append(opts, textDB.In(field).HTML(opt.(string)))
 				}
 				options = append(options, opts)
+
+				answer := -1
+				if len(correct[i]) > 0 {
+					field := fmt.Sprintf("Models[%d].Correct", i)
+					answer = slices.Index(opts, textDB.In(field).HTML(correct[i][0]))
+				}
+				answers = append(answers, answer)
+				screen.Dropdowns++
 			}
 		}
+		if screen.Image.Name != "" || screen.Dropdowns > 0 {
+			screens = append(screens, screen)
+		}
 	}
 
-	var answers []int
-	for i, correct := range question.Correct() {
-		answers = append(answers, slices.Index(options[i], textDB.Get(correct[0])))
-	}
+	rc, explanation := newRecordContext(textDB, question)
 
 	return &LiveScreen{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		ImageName:   slide.View.Image,
-		ImageAlt:    slide.View.Alt,
-		Options:     options,
-		Answers:     answers,
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Screens:       screens,
+		Options:       options,
+		Answers:       answers,
 	}
 }
 
+// ImageHTML returns the image of the first screen, the following ones are
+// part of the dropdowns.
 func (ls *LiveScreen) ImageHTML() string {
-	return fmt.Sprintf(
-		`<img src="%s" alt="%s" class="image">`,
-		ls.ImageName,
-		strings.ReplaceAll(ls.ImageAlt, `"`, `&quot;`),
-	)
+	if len(ls.Screens) == 0 {
+		return ""
+	}
+	return ls.Screens[0].Image.HTML("image")
 }
 
-func (sc *LiveScreen) Record() []string {
-	record := append([]string{},
-		sc.ID, sc.Text, sc.Explanation, sc.Exhibits.HTML())
+// screensHTML renders the dropdowns of every screen in order by using item to
+// render a single one.
+func (ls *LiveScreen) screensHTML(item func(i int) string) string {
+	var b strings.Builder
 
-	var options []string
-	for _, opts := range sc.Options {
-		options = append(options, strings.Join(opts, " ╱ "))
-	}
-	if len(options) > MaxOptions {
-		panic("MaxOptions is too low")
-	}
-	for i := len(options); i < MaxOptions; i++ {
-		options = append(options, "")
+	n := 0
+	for i, screen := range ls.Screens {
+		if i > 0 {
+			b.WriteString(`<div class="screen">`)
+			b.WriteString(screen.Image.HTML("image"))
+		}
+		fmt.Fprintf(&b, `<ol class="dropdowns" start="%d">`, n+1)
+		for j := 0; j < screen.Dropdowns; j++ {
+			b.WriteString(item(n))
+			n++
+		}
+		b.WriteString(`</ol>`)
+		if i > 0 {
+			b.WriteString(`</div>`)
+		}
 	}
+	return b.String()
+}
 
-	record = append(record, options...)
-	answers, _ := json.Marshal(sc.Answers)
-	record = append(record, "liveScreen", sc.ImageHTML(), string(answers))
+func (ls *LiveScreen) DropdownsHTML() string {
+	return ls.screensHTML(func(i int) string {
+		var b strings.Builder
 
-	return record
+		b.WriteString(`<li><select>`)
+		for _, opt := range ls.Options[i] {
+			fmt.Fprintf(&b, `<option>%s</option>`, opt)
+		}
+		b.WriteString(`</select></li>`)
+		return b.String()
+	})
+}
+
+func (ls *LiveScreen) AnswersHTML() string {
+	return ls.screensHTML(func(i int) string {
+		answer := "?"
+		if ls.Answers[i] > -1 {
+			answer = ls.Options[i][ls.Answers[i]]
+		}
+		return fmt.Sprintf(`<li>%s</li>`, answer)
+	})
+}
+
+func (ls *LiveScreen) NoteType() NoteType {
+	return MeasureUpLiveScreen
+}
+
+func (ls *LiveScreen) Record() []string {
+	return []string{
+		ls.ID,
+		ls.Text,
+		ls.Explanation,
+		ls.Exhibits.HTML(),
+		ls.ImageHTML(),
+		ls.DropdownsHTML(),
+		ls.AnswersHTML(),
+		ls.CaseStudyHTML(),
+		ls.Translation,
+		ls.ReferencesHTML(),
+	}
 }
 
 type ContentTable struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
-	Options     []string
-	Answers     []int
+	Exhibits    Exhibits
+	Statements  [][]string
+	Answers     [][]bool
 }
 
 func NewContentTable(
@@ -430,59 +661,86 @@ func NewContentTable(
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
+	exhibits Exhibits,
+	slides QuestionSlides,
 ) *ContentTable {
-	var options []string
-	for _, m := range question.Models {
-		for _, opt := range m.Options.([]interface{}) {
-			opt := opt.(map[string]interface{})
-			options = append(options, textDB.Get(opt["row"].(string)))
+	correct := question.Correct()
+
+	var statements [][]string
+	var answers [][]bool
+	for i, rows := range question.Statements() {
+		var stmts []string
+		var answer []bool
+		for j, row := range rows {
+			field := fmt.Sprintf("Models[%d].Options[%d].row", i, j)
+			stmts = append(stmts, textDB.In(field).HTML(row))
+			answer = append(answer, slices.Index(correct[i], row) > -1)
 		}
+		statements = append(statements, stmts)
+		answers = append(answers, answer)
 	}
 
-	var answers []int
-	for _, correct := range question.Correct()[0] {
-		answers = append(answers, slices.Index(options, textDB.Get(correct)))
-	}
-	slices.Sort(answers)
+	rc, explanation := newRecordContext(textDB, question)
 
 	return &ContentTable{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		Options:     options,
-		Answers:     answers,
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Statements:    statements,
+		Answers:       answers,
 	}
 }
 
-func (ct *ContentTable) Record() []string {
-	record := append([]string{},
-		ct.ID, ct.Text, ct.Explanation, ct.Exhibits.HTML())
-
-	options := append([]string{}, ct.Options...)
-	if len(options) > MaxOptions {
-		panic("MaxOptions is too low")
-	}
-	for i := len(options); i < MaxOptions; i++ {
-		options = append(options, "")
+// TableHTML renders a Yes/No grid per model, with the correct cells marked if
+// withAnswers is set.
+func (ct *ContentTable) TableHTML(withAnswers bool) string {
+	var b strings.Builder
+
+	for i, stmts := range ct.Statements {
+		b.WriteString(`<table class="statements">`)
+		b.WriteString(`<tr><th></th><th>Yes</th><th>No</th></tr>`)
+		for j, stmt := range stmts {
+			yes, no := `<td class="yes">○</td>`, `<td class="no">○</td>`
+			if withAnswers && ct.Answers[i][j] {
+				yes = `<td class="yes correct">●</td>`
+			} else if withAnswers {
+				no = `<td class="no correct">●</td>`
+			}
+			fmt.Fprintf(&b, `<tr class="statement"><td>%s</td>%s%s</tr>`, stmt, yes, no)
+		}
+		b.WriteString(`</table>`)
 	}
+	return b.String()
+}
 
-	record = append(record, options...)
-	answers, _ := json.Marshal(ct.Answers)
-	record = append(record, "contentTable", "", string(answers))
+func (ct *ContentTable) NoteType() NoteType {
+	return MeasureUpContentTable
+}
 
-	return record
+func (ct *ContentTable) Record() []string {
+	return []string{
+		ct.ID,
+		ct.Text,
+		ct.Explanation,
+		ct.Exhibits.HTML(),
+		ct.TableHTML(false),
+		ct.TableHTML(true),
+		ct.CaseStudyHTML(),
+		ct.Translation,
+		ct.ReferencesHTML(),
+	}
 }
 
 type BuildList struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
+	Exhibits    Exhibits
 	Options     []string
 	Answers     []int
 }
@@ -492,13 +750,14 @@ func NewBuildList(
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
+	exhibits Exhibits,
+	slides QuestionSlides,
 ) *BuildList {
 	var options []string
-	for _, m := range question.Models {
-		for _, opt := range m.ByDefault {
-			options = append(options, textDB.Get(opt.Label))
+	for i, m := range question.Models {
+		for j, opt := range m.ByDefault {
+			field := fmt.Sprintf("Models[%d].ByDefault[%d].Label", i, j)
+			options = append(options, textDB.In(field).HTML(opt.Label))
 		}
 	}
 
@@ -508,22 +767,154 @@ func NewBuildList(
 		answers = append(answers, n+1)
 	}
 
+	rc, explanation := newRecordContext(textDB, question)
+
 	return &BuildList{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		Options:     options,
-		Answers:     answers,
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Options:       options,
+		Answers:       answers,
+	}
+}
+
+func (bl *BuildList) ItemsHTML() string {
+	var b strings.Builder
+
+	b.WriteString(`<ul class="items">`)
+	for _, opt := range bl.Options {
+		fmt.Fprintf(&b, `<li>%s</li>`, opt)
+	}
+	b.WriteString(`</ul>`)
+	return b.String()
+}
+
+func (bl *BuildList) StepsHTML() string {
+	var b strings.Builder
+
+	b.WriteString(`<ol class="steps">`)
+	for _, n := range bl.Answers {
+		if n > 0 && n <= len(bl.Options) {
+			fmt.Fprintf(&b, `<li>%s</li>`, bl.Options[n-1])
+		}
 	}
+	b.WriteString(`</ol>`)
+	return b.String()
+}
+
+func (bl *BuildList) NoteType() NoteType {
+	return MeasureUpBuildList
 }
 
 func (bl *BuildList) Record() []string {
+	return []string{
+		bl.ID,
+		bl.Text,
+		bl.Explanation,
+		bl.Exhibits.HTML(),
+		bl.ItemsHTML(),
+		bl.StepsHTML(),
+		bl.CaseStudyHTML(),
+		bl.Translation,
+		bl.ReferencesHTML(),
+	}
+}
+
+type SelectPlaceMup struct {
+	RecordContext
+	ID          string
+	Group       SkillGroup
+	Text        string
+	Explanation string
+	Exhibits    Exhibits
+	Image       QuestionImage
+	Options     [][]string
+	Answers     []int
+}
+
+func NewSelectPlaceMup(
+	id string,
+	textDB TextDB,
+	group SkillGroup,
+	question Question,
+	exhibits Exhibits,
+	slides QuestionSlides,
+) (*SelectPlaceMup, error) {
+	slide := slides.Merged()
+
+	correct := question.Correct()
+
+	var options [][]string
+	answers := make([]int, 0)
+	for i, sel := range slide.SelectPlaceMup {
+		var opts []string
+		for j, opt := range sel.Options {
+			field := fmt.Sprintf("SelectPlaceMup[%d].Options[%d].Alt", i, j)
+			opts = append(opts, textDB.In(field).Resolve(opt.Alt))
+		}
+		options = append(options, opts)
+
+		answer := -1
+		for i, m := range question.Models {
+			if m.Model != sel.ID || len(correct[i]) < 1 {
+				continue
+			}
+			for j, opt := range sel.Options {
+				if opt.ID == correct[i][0] {
+					answer = j
+					break
+				}
+			}
+			if n, err := strconv.Atoi(correct[i][0]); answer < 0 && err == nil {
+				answer = n
+			}
+		}
+		answers = append(answers, answer)
+	}
+
+	if len(slide.Images) != 1 {
+		return nil, fmt.Errorf("select place up has %d images instead of one", len(slide.Images))
+	}
+	img := QuestionImage{
+		Name:     slide.Images[0].Image,
+		Alt:      slide.Images[0].Alt,
+		Original: slide.Images[0].Original,
+	}
+
+	rc, explanation := newRecordContext(textDB, question)
+
+	return &SelectPlaceMup{
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Image:         img,
+		Options:       options,
+		Answers:       answers,
+	}, nil
+}
+
+func (sp *SelectPlaceMup) ImageHTML() string {
+	return sp.Image.HTML("image")
+}
+
+func (sp *SelectPlaceMup) NoteType() NoteType {
+	return MeasureUpCard
+}
+
+func (sp *SelectPlaceMup) Record() []string {
 	record := append([]string{},
-		bl.ID, bl.Text, bl.Explanation, bl.Exhibits.HTML())
+		sp.ID, sp.Text, sp.Explanation, sp.Exhibits.HTML())
 
-	options := append([]string{}, bl.Options...)
+	var options []string
+	for _, opts := range sp.Options {
+		options = append(options, strings.Join(opts, " ╱ "))
+	}
 	if len(options) > MaxOptions {
 		panic("MaxOptions is too low")
 	}
@@ -532,73 +923,88 @@ func (bl *BuildList) Record() []string {
 	}
 
 	record = append(record, options...)
-	answers, _ := json.Marshal(bl.Answers)
-	record = append(record, "buildList", "", string(answers))
+	answers, _ := json.Marshal(sp.Answers)
+	record = append(record, "selectPlaceMup", sp.ImageHTML(), string(answers),
+		sp.CaseStudyHTML(), sp.Translation, sp.ReferencesHTML())
 
 	return record
 }
 
-type SelectPlaceMup struct {
+type DragDropTarget struct {
+	Target  string `json:"target"`
+	Sources []int  `json:"sources"`
+}
+
+type DragDrop struct {
+	RecordContext
 	ID          string
 	Group       SkillGroup
 	Text        string
 	Explanation string
-	Exhibits    QuestionImages
-	ImageName   string
-	ImageAlt    string
+	Exhibits    Exhibits
 	Options     []string
-	Answers     []int
+	Answers     []DragDropTarget
 }
 
-func NewSelectPlaceMup(
+func NewDragDrop(
 	id string,
 	textDB TextDB,
 	group SkillGroup,
 	question Question,
-	images QuestionImages,
-	slide QuestionSlide,
-) *SelectPlaceMup {
+	exhibits Exhibits,
+	slides QuestionSlides,
+) *DragDrop {
+	slide := slides.Merged()
+
 	var options []string
-	for _, sel := range slide.SelectPlaceMup {
-		for _, opt := range sel.Options {
-			options = append(options, opt.Alt)
-		}
+	for i, src := range slide.DragSources {
+		options = append(options, textDB.In(fmt.Sprintf("DragSources[%d].Value", i)).HTML(src.Value))
 	}
 
-	answers := make([]int, 0)
+	correct := question.Correct()
 
-	if len(slide.Images) < 1 {
-		panic("No image for SelectPlaceMup")
-	} else if len(slide.Images) > 1 {
-		panic("Too many images for SelectPlaceMup")
+	var answers []DragDropTarget
+	for i, target := range slide.DragTargets {
+		answer := DragDropTarget{
+			Target:  textDB.In(fmt.Sprintf("DragTargets[%d].Value", i)).HTML(target.Value),
+			Sources: []int{},
+		}
+		for i, m := range question.Models {
+			if m.Model != target.ID {
+				continue
+			}
+			for j, src := range slide.DragSources {
+				if slices.Index(correct[i], src.ID) > -1 {
+					answer.Sources = append(answer.Sources, j+1)
+				}
+			}
+		}
+		answers = append(answers, answer)
 	}
 
-	return &SelectPlaceMup{
-		ID:          id,
-		Group:       group,
-		Text:        textDB.Get(question.Stem.Value),
-		Explanation: textDB.Get(question.Explanation.Value),
-		Exhibits:    images,
-		ImageName:   slide.Images[0].Image,
-		ImageAlt:    slide.Images[0].Alt,
-		Options:     options,
-		Answers:     answers,
+	rc, explanation := newRecordContext(textDB, question)
+
+	return &DragDrop{
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Options:       options,
+		Answers:       answers,
 	}
 }
 
-func (sp *SelectPlaceMup) ImageHTML() string {
-	return fmt.Sprintf(
-		`<img src="%s" alt="%s" class="image">`,
-		sp.ImageName,
-		strings.ReplaceAll(sp.ImageAlt, `"`, `&quot;`),
-	)
+func (dd *DragDrop) NoteType() NoteType {
+	return MeasureUpCard
 }
 
-func (sp *SelectPlaceMup) Record() []string {
+func (dd *DragDrop) Record() []string {
 	record := append([]string{},
-		sp.ID, sp.Text, sp.Explanation, sp.Exhibits.HTML())
+		dd.ID, dd.Text, dd.Explanation, dd.Exhibits.HTML())
 
-	options := append([]string{}, sp.Options...)
+	options := append([]string{}, dd.Options...)
 	if len(options) > MaxOptions {
 		panic("MaxOptions is too low")
 	}
@@ -607,8 +1013,143 @@ func (sp *SelectPlaceMup) Record() []string {
 	}
 
 	record = append(record, options...)
-	answers, _ := json.Marshal(sp.Answers)
-	record = append(record, "selectPlaceMup", sp.ImageHTML(), string(answers))
+	answers, _ := json.Marshal(dd.Answers)
+	record = append(record, "dragDrop", "", string(answers),
+		dd.CaseStudyHTML(), dd.Translation, dd.ReferencesHTML())
+
+	return record
+}
+
+type HotSpot struct {
+	RecordContext
+	ID              string
+	Group           SkillGroup
+	Text            string
+	Explanation     string
+	Exhibits        Exhibits
+	Image           QuestionImage
+	AnswerImageName string
+	Regions         []image.Rectangle
+	Answers         []int
+}
+
+func NewHotSpot(
+	id string,
+	textDB TextDB,
+	group SkillGroup,
+	question Question,
+	exhibits Exhibits,
+	slides QuestionSlides,
+) (*HotSpot, error) {
+	slide := slides.Merged()
+
+	var regions []image.Rectangle
+	for _, hs := range slide.HotSpots {
+		regions = append(regions, image.Rect(
+			int(hs.X),
+			int(hs.Y),
+			int(hs.X+hs.Width),
+			int(hs.Y+hs.Height),
+		))
+	}
+
+	answers := make([]int, 0)
+	for _, correct := range question.Correct() {
+		for i, hs := range slide.HotSpots {
+			if slices.Index(correct, hs.ID) > -1 {
+				answers = append(answers, i)
+			}
+		}
+	}
+	slices.Sort(answers)
+	answers = slices.Compact(answers)
+
+	if len(slide.AllImages()) < 1 {
+		return nil, fmt.Errorf("hotspot has no image")
+	}
+
+	rc, explanation := newRecordContext(textDB, question)
+
+	return &HotSpot{
+		RecordContext: rc,
+		ID:            id,
+		Group:         group,
+		Text:          textDB.In("Stem.Value").HTML(question.Stem.Value),
+		Explanation:   explanation,
+		Exhibits:      exhibits,
+		Image:         slide.AllImages()[0],
+		Regions:       regions,
+		Answers:       answers,
+	}, nil
+}
+
+// ImageRegion returns a region in pixels of the image, which may have been
+// downscaled.
+func (hs *HotSpot) ImageRegion(i int) image.Rectangle {
+	r := hs.Regions[i]
+	if orig := hs.Image.Original; orig != nil && orig.Width > 0 {
+		// The regions refer to the original size.
+		r.Min = r.Min.Mul(orig.ScaledWidth).Div(orig.Width)
+		r.Max = r.Max.Mul(orig.ScaledWidth).Div(orig.Width)
+	}
+	return r
+}
+
+// Annotate writes a copy of the image with the correct regions outlined into
+// the media folder.
+func (hs *HotSpot) Annotate(media string) error {
+	if _, err := os.Stat(filepath.Join(media, hs.Image.Name)); err != nil {
+		// The image is missing, which is reported already.
+		return nil
+	} else if filepath.Ext(hs.Image.Name) == ".svg" {
+		// Placeholders can't be annotated.
+		return nil
+	}
+
+	var regions []image.Rectangle
+	for _, i := range hs.Answers {
+		regions = append(regions, hs.ImageRegion(i))
+	}
+
+	name := annotatedName(hs.Image.Name, regions)
+	err := annotateImage(
+		filepath.Join(media, hs.Image.Name),
+		filepath.Join(media, name),
+		regions,
+	)
+	if err != nil {
+		return err
+	}
+
+	hs.AnswerImageName = name
+	return nil
+}
+
+func (hs *HotSpot) ImageHTML() string {
+	html := hs.Image.HTML("image")
+	if hs.AnswerImageName != "" {
+		html += QuestionImage{
+			Name: hs.AnswerImageName,
+			Alt:  hs.Image.Alt,
+		}.HTML("image answer-image hidden")
+	}
+	return html
+}
+
+func (hs *HotSpot) NoteType() NoteType {
+	return MeasureUpCard
+}
+
+func (hs *HotSpot) Record() []string {
+	record := append([]string{},
+		hs.ID, hs.Text, hs.Explanation, hs.Exhibits.HTML())
+
+	options := make([]string, MaxOptions)
+
+	record = append(record, options...)
+	answers, _ := json.Marshal(hs.Answers)
+	record = append(record, "hotspot", hs.ImageHTML(), string(answers),
+		hs.CaseStudyHTML(), hs.Translation, hs.ReferencesHTML())
 
 	return record
 }
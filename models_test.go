@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// decode decodes a JSON document as found in a dump into v.
+func decode(t *testing.T, doc string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(doc), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testTextDB(t *testing.T, texts map[string]string) TextDB {
+	t.Helper()
+	buf, err := json.Marshal(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var db TextDB
+	decode(t, string(buf), &db)
+	return db.For("q1")
+}
+
+func TestNewDragDrop(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{
+		"src1": "Source one", "src2": "Source two", "src3": "Source three",
+		"tgt1": "Target one", "tgt2": "Target two",
+	})
+
+	var question Question
+	decode(t, `{
+		"Stem": {"Value": "Match"},
+		"Models": [
+			{"Model": "T1", "Correct": ["S2"]},
+			{"Model": "T2", "Correct": ["S1", "S3"]}
+		]
+	}`, &question)
+
+	var slide QuestionSlide
+	decode(t, `{
+		"DragSources": [
+			{"ID": "S1", "Value": "$$src1"},
+			{"ID": "S2", "Value": "$$src2"},
+			{"ID": "S3", "Value": "$$src3"}
+		],
+		"DragTargets": [
+			{"ID": "T1", "Value": "$$tgt1"},
+			{"ID": "T2", "Value": "$$tgt2"},
+			{"ID": "T3", "Value": "Distractor"}
+		]
+	}`, &slide)
+
+	dd := NewDragDrop("1", textDB, SkillGroup{}, question, nil, QuestionSlides{slide})
+
+	if want := []string{"Source one", "Source two", "Source three"}; !reflect.DeepEqual(dd.Options, want) {
+		t.Errorf("options = %q, want %q", dd.Options, want)
+	}
+	want := []DragDropTarget{
+		{Target: "Target one", Sources: []int{2}},
+		{Target: "Target two", Sources: []int{1, 3}},
+		{Target: "Distractor", Sources: []int{}},
+	}
+	if !reflect.DeepEqual(dd.Answers, want) {
+		t.Errorf("answers = %v, want %v", dd.Answers, want)
+	}
+	answer := dd.Record()[slices.Index(CSVColumns(), "Answer")]
+	if answer != `[{"target":"Target one","sources":[2]},{"target":"Target two","sources":[1,3]},{"target":"Distractor","sources":[]}]` {
+		t.Errorf("answer column = %s", answer)
+	}
+}
+
+func TestNewHotSpot(t *testing.T) {
+	textDB := testTextDB(t, nil)
+
+	var question Question
+	decode(t, `{"Models": [{"Model": "m", "Correct": ["H2"]}]}`, &question)
+
+	var slide QuestionSlide
+	decode(t, `{
+		"View": {"Image": "screen.png"},
+		"HotSpots": [
+			{"ID": "H1", "X": 0, "Y": 0, "Width": 10, "Height": 10},
+			{"ID": "H2", "X": 20, "Y": 5, "Width": 10, "Height": 5}
+		]
+	}`, &slide)
+
+	hs, err := NewHotSpot("1", textDB, SkillGroup{}, question, nil, QuestionSlides{slide})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1}; !reflect.DeepEqual(hs.Answers, want) {
+		t.Errorf("answers = %v, want %v", hs.Answers, want)
+	}
+	if want := image.Rect(20, 5, 30, 10); hs.Regions[1] != want {
+		t.Errorf("region = %v, want %v", hs.Regions[1], want)
+	}
+
+	slide.View.Image = ""
+	if _, err := NewHotSpot("1", textDB, SkillGroup{}, question, nil, QuestionSlides{slide}); err == nil {
+		t.Error("expected an error for a hotspot without image")
+	}
+}
+
+func TestHotSpotAnnotate(t *testing.T) {
+	media := t.TempDir()
+
+	f, err := os.Create(filepath.Join(media, "screen.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	hs := &HotSpot{
+		Image:   QuestionImage{Name: "screen.png"},
+		Regions: []image.Rectangle{image.Rect(0, 0, 10, 10), image.Rect(20, 5, 30, 15)},
+		Answers: []int{1},
+	}
+	if err := hs.Annotate(media); err != nil {
+		t.Fatal(err)
+	}
+	if hs.AnswerImageName == "" {
+		t.Fatal("no answer image was written")
+	}
+
+	f, err = os.Open(filepath.Join(media, hs.AnswerImageName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(img.At(20, 10)); c != regionColor {
+		t.Errorf("border of the correct region = %v, want %v", c, regionColor)
+	}
+	if c := color.RGBAModel.Convert(img.At(5, 5)); c == regionColor {
+		t.Error("the wrong region was outlined")
+	}
+
+	broken := &HotSpot{Image: QuestionImage{Name: "broken.png"}}
+	os.WriteFile(filepath.Join(media, "broken.png"), []byte("no image"), 0o644)
+	if err := broken.Annotate(media); err == nil {
+		t.Error("expected an error for a corrupt image")
+	}
+}
+
+func TestNewSelectPlaceMup(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{"b": `<b style="x">B</b>`})
+
+	var question Question
+	decode(t, `{"Models": [
+		{"Model": "Z1", "Correct": "O2"},
+		{"Model": "Z2", "Correct": "0"},
+		{"Model": "Z3", "Correct": []}
+	]}`, &question)
+
+	var slide QuestionSlide
+	decode(t, `{
+		"Images": [{"Image": "map.png", "Alt": "Map"}],
+		"SelectPlaceMup": [
+			{"ID": "Z1", "Options": [{"ID": "O1", "Alt": "a"}, {"ID": "O2", "Alt": "$$b"}]},
+			{"ID": "Z2", "Options": [{"ID": "O3", "Alt": "c${x}<script>x</script>"}, {"ID": "O4", "Alt": "d"}]},
+			{"ID": "Z3", "Options": [{"ID": "O5", "Alt": "e"}]}
+		]
+	}`, &slide)
+
+	sp, err := NewSelectPlaceMup("1", textDB, SkillGroup{}, question, nil, QuestionSlides{slide})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 0, -1}; !reflect.DeepEqual(sp.Answers, want) {
+		t.Errorf("answers = %v, want %v", sp.Answers, want)
+	}
+	if want := [][]string{{"a", "<b>B</b>"}, {"c$&#123;x}", "d"}, {"e"}}; !reflect.DeepEqual(sp.Options, want) {
+		t.Errorf("options = %q, want %q", sp.Options, want)
+	}
+	if sp.Image.Name != "map.png" {
+		t.Errorf("image = %q, want map.png", sp.Image.Name)
+	}
+
+	slide.Images = nil
+	if _, err := NewSelectPlaceMup("1", textDB, SkillGroup{}, question, nil, QuestionSlides{slide}); err == nil {
+		t.Error("expected an error for a select place up without image")
+	}
+}
+
+func TestNewContentTable(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{"s1": "First", "s2": "Second"})
+
+	var question Question
+	decode(t, `{"Models": [{"Options": [
+		{"row": "$$s1", "correct": "Yes"},
+		{"row": "$$s2", "correct": "no"}
+	]}]}`, &question)
+
+	ct := NewContentTable("1", textDB, SkillGroup{}, question, nil, nil)
+	if want := [][]string{{"First", "Second"}}; !reflect.DeepEqual(ct.Statements, want) {
+		t.Errorf("statements = %q, want %q", ct.Statements, want)
+	}
+	if want := [][]bool{{true, false}}; !reflect.DeepEqual(ct.Answers, want) {
+		t.Errorf("answers = %v, want %v", ct.Answers, want)
+	}
+
+	front, back := ct.TableHTML(false), ct.TableHTML(true)
+	if strings.Contains(front, "correct") {
+		t.Errorf("front reveals the answers: %s", front)
+	}
+	for _, want := range []string{
+		`<td>First</td><td class="yes correct">●</td><td class="no">○</td>`,
+		`<td>Second</td><td class="yes">○</td><td class="no correct">●</td>`,
+	} {
+		if !strings.Contains(back, want) {
+			t.Errorf("back lacks %s:\n%s", want, back)
+		}
+	}
+}
+
+func TestCaseStudyOptions(t *testing.T) {
+	var slide QuestionSlide
+	decode(t, `{"CaseStudy": [
+		{"Options": []},
+		{"Options": [
+			{"Label": "Overview", "View": "v1"},
+			{"Label": "Questions", "Options": [
+				{"CSContext": "a"},
+				{"CSContext": "b", "Options": [{"CSContext": "c"}]}
+			]}
+		]},
+		{"Options": [{"CSContext": "a"}, {"Label": "Empty"}]}
+	]}`, &slide)
+
+	opts, err := slide.CaseStudyOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, opt := range opts {
+		got = append(got, opt.View+opt.CSContext)
+	}
+	if want := []string{"v1", "a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("options = %v, want %v", got, want)
+	}
+
+	for _, doc := range []string{
+		`{}`,
+		`{"CaseStudy": [{"Options": [{"View": "v1"}]}]}`,
+	} {
+		var slide QuestionSlide
+		decode(t, doc, &slide)
+		if _, err := slide.CaseStudyOptions(); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
+
+func TestSlideNames(t *testing.T) {
+	var question Question
+	decode(t, `{
+		"StartSlide": {"Value": "views/s1"},
+		"Slides": [{"Value": "views/s1"}, {"Value": ""}, {"Value": "views/s2"}, {"Value": "views/s2"}]
+	}`, &question)
+
+	if got, want := question.SlideNames(), []string{"views/s1", "views/s2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("slide names = %v, want %v", got, want)
+	}
+}
+
+func TestNewLiveScreenSlides(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{"x": "x", "y": "y", "p": "p", "q": "q"})
+
+	var question Question
+	decode(t, `{"Models": [
+		{"Model": "d1", "Options": ["x", "y"], "Correct": "y"},
+		{"Model": "d2", "Options": ["p", "q"], "Correct": []}
+	]}`, &question)
+
+	var first, second QuestionSlide
+	decode(t, `{"View": {"Image": "one.png"}, "Selects": [{"ID": "s", "Model": "d1"}]}`, &first)
+	decode(t, `{"View": {"Image": "two.png"}, "Selects": [{"ID": "s", "Model": "d2"}]}`, &second)
+
+	ls := NewLiveScreen("1", textDB, SkillGroup{}, question, nil, QuestionSlides{first, second})
+	if len(ls.Screens) != 2 || ls.Screens[1].Image.Name != "two.png" || ls.Screens[1].Dropdowns != 1 {
+		t.Fatalf("screens = %+v, want one per slide", ls.Screens)
+	}
+	if want := []int{1, -1}; !reflect.DeepEqual(ls.Answers, want) {
+		t.Errorf("answers = %v, want %v", ls.Answers, want)
+	}
+	if got := ls.AnswersHTML(); !strings.Contains(got, `<li>y</li>`) || !strings.Contains(got, `<li>?</li>`) {
+		t.Errorf("answers = %s, want y and an unknown one", got)
+	}
+
+	merged := QuestionSlides{first, second}.Merged()
+	if merged.View.Image != "one.png" || len(merged.Selects) != 2 {
+		t.Errorf("merged = %+v, want the first view and both selects", merged)
+	}
+}
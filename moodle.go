@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var imgTag = regexp.MustCompile(`(?i)<img[^>]*>`)
+
+// minMatchingPairs is the least number of pairs Moodle imports a matching
+// question with.
+const minMatchingPairs = 3
+
+// moodleCategory returns the category of a skill group below the test's.
+func moodleCategory(testName string, group SkillGroup) string {
+	name := strings.ReplaceAll(group.Name, "/", "-")
+	return "$course$/top/" + testName + "/" + name
+}
+
+// moodleFraction returns the share of a correct answer among n as one of the
+// grades Moodle accepts.
+func moodleFraction(n int) string {
+	s := strconv.FormatFloat(100/float64(max(n, 1)), 'f', 5, 64)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// giftEscape escapes the characters GIFT reserves and puts the text on a
+// single line.
+func giftEscape(s string) string {
+	s = strings.NewReplacer(
+		`\`, `\\`, "~", `\~`, "=", `\=`, "#", `\#`, "{", `\{`, "}", `\}`, ":", `\:`,
+	).Replace(s)
+	return strings.TrimSpace(whitespace.ReplaceAllString(s, " "))
+}
+
+// giftText returns the text of a question for GIFT, which can't carry images.
+func giftText(parts QuestionParts) string {
+	text := parts.Text
+	for _, e := range parts.Exhibits {
+		if e.Kind != ExhibitImage {
+			text += "<br>" + e.HTML()
+		}
+	}
+	return giftEscape(imgTag.ReplaceAllString(text, ""))
+}
+
+// writeGIFT writes the records which GIFT can express: choices, and build
+// lists, drag and drops and content tables as matchings.
+func writeGIFT(path string, testName string, records []Record) error {
+	var b strings.Builder
+	category := ""
+
+	for _, record := range records {
+		parts := partsOf(record)
+
+		var answers []string
+		switch r := record.(type) {
+		case *SingleChoice:
+			for i, opt := range r.Options {
+				prefix := "~"
+				if i+1 == r.Answer {
+					prefix = "="
+				}
+				answers = append(answers, prefix+giftEscape(opt))
+			}
+		case *MultipleChoice:
+			fraction := moodleFraction(len(r.Answers))
+			for i, opt := range r.Options {
+				weight := "-100"
+				for _, n := range r.Answers {
+					if n == i+1 {
+						weight = fraction
+					}
+				}
+				answers = append(answers, "~%"+weight+"%"+giftEscape(opt))
+			}
+		case *BuildList:
+			for i, n := range r.Answers {
+				if n > 0 && n <= len(r.Options) {
+					answers = append(answers, fmt.Sprintf("=%s -> %d", giftEscape(r.Options[n-1]), i+1))
+				}
+			}
+		case *DragDrop:
+			for _, a := range r.Answers {
+				for _, n := range a.Sources {
+					if n > 0 && n <= len(r.Options) {
+						answers = append(answers, "="+giftEscape(r.Options[n-1])+" -> "+giftEscape(a.Target))
+					}
+				}
+			}
+		case *ContentTable:
+			for i, stmts := range r.Statements {
+				for j, stmt := range stmts {
+					answer := "No"
+					if r.Answers[i][j] {
+						answer = "Yes"
+					}
+					answers = append(answers, "="+giftEscape(stmt)+" -> "+answer)
+				}
+			}
+		}
+		switch record.(type) {
+		case *BuildList, *DragDrop, *ContentTable:
+			if len(answers) < minMatchingPairs {
+				log.Printf("Skipping %s (%s) for GIFT: %d pairs are too few for a matching\n",
+					parts.ID, parts.Kind, len(answers))
+				continue
+			}
+		}
+		if len(answers) == 0 {
+			log.Printf("Skipping %s (%s) for GIFT\n", parts.ID, parts.Kind)
+			continue
+		}
+
+		if c := moodleCategory(testName, parts.Group); c != category {
+			category = c
+			fmt.Fprintf(&b, "$CATEGORY: %s\n\n", category)
+		}
+
+		fmt.Fprintf(&b, "::%s::[html]%s {\n", giftEscape(parts.ID), giftText(parts))
+		for _, answer := range answers {
+			fmt.Fprintf(&b, "\t%s\n", answer)
+		}
+		if parts.Explanation != "" {
+			fmt.Fprintf(&b, "\t####%s\n", giftEscape(parts.Explanation))
+		}
+		b.WriteString("}\n\n")
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+type moodleCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type moodleFile struct {
+	Name     string `xml:"name,attr"`
+	Path     string `xml:"path,attr"`
+	Encoding string `xml:"encoding,attr"`
+	Data     string `xml:",chardata"`
+}
+
+type moodleText struct {
+	Format string       `xml:"format,attr,omitempty"`
+	Text   moodleCDATA  `xml:"text"`
+	Files  []moodleFile `xml:"file"`
+}
+
+type moodleAnswer struct {
+	Fraction string      `xml:"fraction,attr"`
+	Format   string      `xml:"format,attr"`
+	Text     moodleCDATA `xml:"text"`
+}
+
+type moodleSubquestion struct {
+	Format string      `xml:"format,attr"`
+	Text   moodleCDATA `xml:"text"`
+	Answer struct {
+		Text moodleCDATA `xml:"text"`
+	} `xml:"answer"`
+}
+
+type moodleDrag struct {
+	No        int    `xml:"no"`
+	Text      string `xml:"text"`
+	NoOfDrags int    `xml:"noofdrags"`
+}
+
+type moodleDrop struct {
+	Shape  string `xml:"shape"`
+	Coords string `xml:"coords"`
+	Choice int    `xml:"choice"`
+}
+
+type moodleQuestion struct {
+	Type            string              `xml:"type,attr"`
+	Category        *moodleText         `xml:"category"`
+	Name            *moodleText         `xml:"name"`
+	QuestionText    *moodleText         `xml:"questiontext"`
+	GeneralFeedback *moodleText         `xml:"generalfeedback"`
+	DefaultGrade    string              `xml:"defaultgrade,omitempty"`
+	Single          string              `xml:"single,omitempty"`
+	ShuffleAnswers  string              `xml:"shuffleanswers,omitempty"`
+	Answers         []moodleAnswer      `xml:"answer"`
+	Subquestions    []moodleSubquestion `xml:"subquestion"`
+	Background      []moodleFile        `xml:"file"`
+	Drags           []moodleDrag        `xml:"drag"`
+	Drops           []moodleDrop        `xml:"drop"`
+	Tags            *moodleTags         `xml:"tags"`
+}
+
+type moodleTags struct {
+	Tags []moodleText `xml:"tag"`
+}
+
+type moodleQuiz struct {
+	XMLName   xml.Name         `xml:"quiz"`
+	Questions []moodleQuestion `xml:"question"`
+}
+
+// moodleFiles embeds the media which is referenced by html.
+type moodleFiles struct {
+	media string
+	err   error
+}
+
+func (mf *moodleFiles) file(name string) moodleFile {
+	buf, err := os.ReadFile(filepath.Join(mf.media, name))
+	if err != nil && mf.err == nil {
+		mf.err = err
+	}
+	return moodleFile{
+		Name:     name,
+		Path:     "/",
+		Encoding: "base64",
+		Data:     base64.StdEncoding.EncodeToString(buf),
+	}
+}
+
+// text returns an HTML text whose media is embedded and referred to as a file
+// of the question. References to anything but a file of the media folder are
+// left as they are.
+func (mf *moodleFiles) text(html string) *moodleText {
+	t := &moodleText{Format: "html"}
+
+	var names []string
+	html = mediaRef.ReplaceAllStringFunc(html, func(ref string) string {
+		name := mediaName(mediaRef.FindStringSubmatch(ref))
+		if info, err := os.Stat(filepath.Join(mf.media, name)); err != nil || info.IsDir() {
+			return ref
+		}
+		names = append(names, name)
+		return strings.Replace(ref, name, "@@PLUGINFILE@@/"+name, 1)
+	})
+	for i, name := range names {
+		if !slices.Contains(names[:i], name) {
+			t.Files = append(t.Files, mf.file(name))
+		}
+	}
+
+	t.Text.Value = html
+	return t
+}
+
+// clozeEscape escapes the characters which have a meaning inside an embedded
+// answer of a cloze question.
+func clozeEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "}", `\}`, "#", `\#`, "~", `\~`, "/", `\/`, `"`, `\"`,
+	).Replace(s)
+}
+
+func clozeDropdown(options []string, answer int) (string, error) {
+	if answer < 0 || answer >= len(options) {
+		return "", fmt.Errorf("the answer of a dropdown is unknown")
+	}
+
+	var choices []string
+	for i, opt := range options {
+		prefix := "~"
+		if i == answer {
+			prefix = "~="
+		}
+		choices = append(choices, prefix+clozeEscape(opt))
+	}
+	return "{1:MULTICHOICE:" + strings.TrimPrefix(strings.Join(choices, ""), "~") + "}", nil
+}
+
+// moodleQuestionOf maps a record to the nearest Moodle question type: choices
+// to multichoice, build lists, drag and drops and content tables to matching,
+// live screens and select place ups to cloze and hotspots to drag and drop
+// markers. It fails for questions Moodle wouldn't import.
+func moodleQuestionOf(record Record, files *moodleFiles) (moodleQuestion, error) {
+	parts := partsOf(record)
+	text := parts.Text
+	if exhibits := parts.Exhibits.HTML(); exhibits != "" {
+		text += "<br>" + exhibits
+	}
+	if cs := parts.Context.CaseStudyHTML(); cs != "" {
+		text += cs
+	}
+
+	q := moodleQuestion{
+		Name:            &moodleText{Text: moodleCDATA{parts.ID}},
+		GeneralFeedback: files.text(parts.Explanation + parts.Context.ReferencesHTML()),
+		DefaultGrade:    "1",
+	}
+	for _, tag := range record.Tags() {
+		if q.Tags == nil {
+			q.Tags = &moodleTags{}
+		}
+		q.Tags.Tags = append(q.Tags.Tags, moodleText{Text: moodleCDATA{tag}})
+	}
+
+	var err error
+	matching := func(pairs [][2]string) {
+		if len(pairs) < minMatchingPairs {
+			err = fmt.Errorf("%d pairs are too few for a matching", len(pairs))
+		}
+		q.Type = "matching"
+		q.ShuffleAnswers = "true"
+		for _, p := range pairs {
+			sq := moodleSubquestion{Format: "html", Text: moodleCDATA{p[0]}}
+			sq.Answer.Text.Value = p[1]
+			q.Subquestions = append(q.Subquestions, sq)
+		}
+	}
+
+	switch r := record.(type) {
+	case *SingleChoice:
+		q.Type, q.Single, q.ShuffleAnswers = "multichoice", "true", "true"
+		for i, opt := range r.Options {
+			fraction := "0"
+			if i+1 == r.Answer {
+				fraction = "100"
+			}
+			q.Answers = append(q.Answers, moodleAnswer{fraction, "html", moodleCDATA{opt}})
+		}
+	case *MultipleChoice:
+		q.Type, q.Single, q.ShuffleAnswers = "multichoice", "false", "true"
+		fraction := moodleFraction(len(r.Answers))
+		for i, opt := range r.Options {
+			f := "-100"
+			for _, n := range r.Answers {
+				if n == i+1 {
+					f = fraction
+				}
+			}
+			q.Answers = append(q.Answers, moodleAnswer{f, "html", moodleCDATA{opt}})
+		}
+	case *BuildList:
+		var pairs [][2]string
+		for i, n := range r.Answers {
+			if n > 0 && n <= len(r.Options) {
+				pairs = append(pairs, [2]string{r.Options[n-1], strconv.Itoa(i + 1)})
+			}
+		}
+		matching(pairs)
+	case *DragDrop:
+		var pairs [][2]string
+		for _, a := range r.Answers {
+			for _, n := range a.Sources {
+				if n > 0 && n <= len(r.Options) {
+					pairs = append(pairs, [2]string{r.Options[n-1], a.Target})
+				}
+			}
+			if len(a.Sources) == 0 {
+				// A distractor.
+				pairs = append(pairs, [2]string{"", a.Target})
+			}
+		}
+		matching(pairs)
+	case *ContentTable:
+		var pairs [][2]string
+		for i, stmts := range r.Statements {
+			for j, stmt := range stmts {
+				answer := "No"
+				if r.Answers[i][j] {
+					answer = "Yes"
+				}
+				pairs = append(pairs, [2]string{stmt, answer})
+			}
+		}
+		matching(pairs)
+	case *LiveScreen:
+		q.Type = "cloze"
+		text += r.ImageHTML() + r.screensHTML(func(i int) string {
+			dropdown, e := clozeDropdown(r.Options[i], r.Answers[i])
+			if e != nil {
+				err = e
+			}
+			return "<li>" + dropdown + "</li>"
+		})
+	case *SelectPlaceMup:
+		q.Type = "cloze"
+		var zones []string
+		for i, opts := range r.Options {
+			dropdown, e := clozeDropdown(opts, r.Answers[i])
+			if e != nil {
+				err = e
+			}
+			zones = append(zones, dropdown)
+		}
+		text += r.ImageHTML() + listHTML("ol", zones)
+	case *HotSpot:
+		q.Type = "ddmarker"
+		q.ShuffleAnswers = "0"
+		q.Background = append(q.Background, files.file(r.Image.Name))
+		q.Drags = append(q.Drags, moodleDrag{No: 1, Text: "✓", NoOfDrags: len(r.Answers)})
+		for _, i := range r.Answers {
+			region := r.ImageRegion(i)
+			q.Drops = append(q.Drops, moodleDrop{
+				Shape:  "rectangle",
+				Coords: fmt.Sprintf("%d,%d;%d,%d", region.Min.X, region.Min.Y, region.Dx(), region.Dy()),
+				Choice: 1,
+			})
+		}
+	}
+
+	if err != nil {
+		return q, err
+	}
+	q.QuestionText = files.text(text)
+	return q, nil
+}
+
+// writeMoodleXML writes the records as a Moodle XML question bank, embedding
+// their media.
+func writeMoodleXML(path string, testName string, records []Record, media string) error {
+	files := &moodleFiles{media: media}
+	var quiz moodleQuiz
+	category := ""
+
+	for _, record := range records {
+		parts := partsOf(record)
+		q, err := moodleQuestionOf(record, files)
+		if err != nil {
+			log.Printf("Skipping %s (%s) for Moodle: %v\n", parts.ID, parts.Kind, err)
+			continue
+		}
+
+		if c := moodleCategory(testName, parts.Group); c != category {
+			category = c
+			quiz.Questions = append(quiz.Questions, moodleQuestion{
+				Type:     "category",
+				Category: &moodleText{Text: moodleCDATA{category}},
+			})
+		}
+		quiz.Questions = append(quiz.Questions, q)
+	}
+	if files.err != nil {
+		return files.err
+	}
+
+	buf, err := xml.MarshalIndent(quiz, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), buf...), 0o644)
+}
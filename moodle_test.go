@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/xml"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGIFTEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{`C:\Windows`, `C\:\\Windows`},
+		{"a~b=c#d", `a\~b\=c\#d`},
+		{"{x}", `\{x\}`},
+		{"Note: 1", `Note\: 1`},
+		{"  two\n lines  ", "two lines"},
+		{`<a href="https://a.com">x</a>`, `<a href\="https\://a.com">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := giftEscape(tt.text); got != tt.want {
+			t.Errorf("giftEscape(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestClozeEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{`C:\Windows`, `C:\\Windows`},
+		{"{a}", `{a\}`},
+		{"#1 ~2", `\#1 \~2`},
+		{"a/b", `a\/b`},
+		{`say "hi"`, `say \"hi\"`},
+	}
+	for _, tt := range tests {
+		if got := clozeEscape(tt.text); got != tt.want {
+			t.Errorf("clozeEscape(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestClozeDropdown(t *testing.T) {
+	got, err := clozeDropdown([]string{"a", "b/c", "d"}, 1)
+	if want := `{1:MULTICHOICE:a~=b\/c~d}`; err != nil || got != want {
+		t.Errorf("clozeDropdown() = %q, %v, want %q", got, err, want)
+	}
+	got, err = clozeDropdown([]string{"a", "b"}, 0)
+	if want := `{1:MULTICHOICE:=a~b}`; err != nil || got != want {
+		t.Errorf("clozeDropdown() = %q, %v, want %q", got, err, want)
+	}
+	for _, answer := range []int{-1, 2} {
+		if _, err := clozeDropdown([]string{"a", "b"}, answer); err == nil {
+			t.Errorf("expected an error for answer %d", answer)
+		}
+	}
+}
+
+func TestWriteGIFT(t *testing.T) {
+	group := SkillGroup{ID: 1, Name: "One/Two"}
+	records := []Record{
+		&SingleChoice{ID: "1", Group: group, Text: "Which?", Options: []string{"a", "b=c"}, Answer: 2, Explanation: "So."},
+		&MultipleChoice{ID: "2", Group: group, Text: "Which ones?", Options: []string{"a", "b", "c"}, Answers: []int{1, 3}},
+		&BuildList{ID: "3", Group: group, Text: "Order", Options: []string{"x", "y", "z"}, Answers: []int{3, 1, 2}},
+		&ContentTable{ID: "4", Group: group, Text: "Yes or no", Statements: [][]string{{"s1", "s2"}}, Answers: [][]bool{{true, false}}},
+		&LiveScreen{ID: "5", Group: group, Options: [][]string{{"a"}}, Answers: []int{0}},
+	}
+
+	path := filepath.Join(t.TempDir(), "t1.gift.txt")
+	if err := writeGIFT(path, "T1", records); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `$CATEGORY: $course$/top/T1/One-Two
+
+::1::[html]Which? {
+	~a
+	=b\=c
+	####So.
+}
+
+::2::[html]Which ones? {
+	~%50%a
+	~%-100%b
+	~%50%c
+}
+
+::3::[html]Order {
+	=z -> 1
+	=x -> 2
+	=y -> 3
+}
+
+`
+	if string(got) != want {
+		t.Errorf("GIFT:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteMoodleXML(t *testing.T) {
+	media := t.TempDir()
+	for _, name := range []string{"screen.png", "map.png"} {
+		if err := os.WriteFile(filepath.Join(media, name), testPNG(t, 40, 20), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	group := SkillGroup{ID: 1, Name: "One"}
+	records := []Record{
+		&SingleChoice{
+			ID: "1", Group: group, Options: []string{"a", "b"}, Answer: 1,
+			Text: `Which? See <a href="#ex">the exhibit</a>, <img src="screen.png"> and <img src="gone.png">`,
+		},
+		&SelectPlaceMup{
+			ID: "2", Group: group, Image: QuestionImage{Name: "map.png"},
+			Options: [][]string{{"a", "b"}, {"c"}}, Answers: []int{1, 0},
+		},
+		&SelectPlaceMup{
+			ID: "3", Group: group, Image: QuestionImage{Name: "map.png"},
+			Options: [][]string{{"a", "b"}}, Answers: []int{-1},
+		},
+		&LiveScreen{
+			ID: "4", Group: group, Screens: []LiveScreenScreen{{Dropdowns: 1}},
+			Options: [][]string{{"a", "b"}}, Answers: []int{-1},
+		},
+		&DragDrop{
+			ID: "5", Group: group, Options: []string{"a", "b"},
+			Answers: []DragDropTarget{{Target: "t1", Sources: []int{1}}, {Target: "t2", Sources: []int{2}}},
+		},
+		&HotSpot{
+			ID: "6", Group: group, Image: QuestionImage{Name: "screen.png"},
+			Regions: []image.Rectangle{image.Rect(0, 0, 10, 10), image.Rect(20, 5, 30, 10)},
+			Answers: []int{1},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "t1.moodle.xml")
+	if err := writeMoodleXML(path, "T1", records, media); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var quiz struct {
+		Questions []struct {
+			Type         string `xml:"type,attr"`
+			Name         string `xml:"name>text"`
+			QuestionText struct {
+				Text  string   `xml:"text"`
+				Files []string `xml:"file"`
+			} `xml:"questiontext"`
+			Drops []string `xml:"drop>coords"`
+		} `xml:"question"`
+	}
+	if err := xml.Unmarshal(buf, &quiz); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, q := range quiz.Questions {
+		names = append(names, q.Type+" "+q.Name)
+	}
+	if got, want := strings.Join(names, ", "), "category , multichoice 1, cloze 2, ddmarker 6"; got != want {
+		t.Fatalf("questions = %s, want %s", got, want)
+	}
+	single := quiz.Questions[1].QuestionText
+	if !strings.Contains(single.Text, `<a href="#ex">`) || !strings.Contains(single.Text, `src="gone.png"`) ||
+		!strings.Contains(single.Text, `src="@@PLUGINFILE@@/screen.png"`) || len(single.Files) != 1 {
+		t.Errorf("text = %s with %d files, want only the image of the media folder embedded", single.Text, len(single.Files))
+	}
+	cloze := quiz.Questions[2].QuestionText
+	if !strings.Contains(cloze.Text, "<li>{1:MULTICHOICE:a~=b}</li><li>{1:MULTICHOICE:=c}</li>") {
+		t.Errorf("cloze = %s, want a dropdown per zone", cloze.Text)
+	}
+	if !strings.Contains(cloze.Text, `src="@@PLUGINFILE@@/map.png"`) || len(cloze.Files) != 1 {
+		t.Errorf("cloze = %s with %d files, want the image embedded", cloze.Text, len(cloze.Files))
+	}
+	if drops := quiz.Questions[3].Drops; len(drops) != 1 || drops[0] != "20,5;10,5" {
+		t.Errorf("drops = %v, want the correct region", drops)
+	}
+}
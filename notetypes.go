@@ -0,0 +1,44 @@
+package main
+
+// NoteType describes an Anki note type and the order of its fields.
+type NoteType struct {
+	Name    string
+	Columns []string
+}
+
+var (
+	// MeasureUpCard is the universal note type which branches on its Type
+	// field to render an answer.
+	MeasureUpCard = NoteType{
+		Name:    "MeasureUpCard",
+		Columns: CSVColumns(),
+	}
+	MeasureUpBuildList = NoteType{
+		Name: "MeasureUpBuildList",
+		Columns: []string{
+			"ID", "Text", "Explanation", "Exhibits", "Items", "Steps", "CaseStudy",
+			"Translation", "References",
+		},
+	}
+	MeasureUpLiveScreen = NoteType{
+		Name: "MeasureUpLiveScreen",
+		Columns: []string{
+			"ID", "Text", "Explanation", "Exhibits", "Image", "Dropdowns", "Answers",
+			"CaseStudy", "Translation", "References",
+		},
+	}
+	MeasureUpContentTable = NoteType{
+		Name: "MeasureUpContentTable",
+		Columns: []string{
+			"ID", "Text", "Explanation", "Exhibits", "Statements", "Answers",
+			"CaseStudy", "Translation", "References",
+		},
+	}
+)
+
+var NoteTypes = []NoteType{
+	MeasureUpCard,
+	MeasureUpBuildList,
+	MeasureUpLiveScreen,
+	MeasureUpContentTable,
+}
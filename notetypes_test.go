@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestRecordsMatchNoteTypes(t *testing.T) {
+	records := []Record{
+		&SingleChoice{Options: []string{"a", "b"}, Answer: 1},
+		&MultipleChoice{Options: []string{"a", "b"}, Answers: []int{1, 2}},
+		&LiveScreen{},
+		&ContentTable{},
+		&BuildList{},
+		&SelectPlaceMup{},
+		&DragDrop{},
+		&HotSpot{},
+	}
+	for _, record := range records {
+		noteType := record.NoteType()
+		if got, want := len(record.Record()), len(noteType.Columns); got != want {
+			t.Errorf("%T has %d fields, note type %s has %d columns", record, got, noteType.Name, want)
+		}
+	}
+}
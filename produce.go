@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -16,56 +17,217 @@ var skillGroup2questionType = map[string]string{
 	"simulation":     "liveScreen",
 }
 
-func removeEscapes(b []byte) []byte {
-	s := string(b)
-	s = strings.ReplaceAll(s, "\\\\", "")
-	s = strings.ReplaceAll(s, "<br />", "<br>")
-	return []byte(s)
-}
-
 func readJSON(path string, out interface{}) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(removeEscapes(data), out)
+	return json.Unmarshal(data, out)
+}
+
+// readSlide reads a dumped slide and copies its images into the media folder.
+func readSlide(
+	src string,
+	copier *mediaCopier,
+	questionName string,
+	slideName string,
+) (QuestionSlide, error) {
+	var slide QuestionSlide
+
+	parts := strings.Split(slideName, "/")
+	sfp := filepath.Join(src, "slides", parts[len(parts)-1]+".json")
+
+	if err := readJSON(sfp, &slide); err != nil {
+		return slide, err
+	} else if slide.View.Image != "" {
+		image := slide.ViewImage()
+		copier.Copy(questionName, &image)
+		slide.View.Image, slide.View.Original = image.Name, image.Original
+	}
+	for i := range slide.Images {
+		image := QuestionImage{
+			Name: slide.Images[i].Image,
+			Alt:  slide.Images[i].Alt,
+		}
+		copier.Copy(questionName, &image)
+		slide.Images[i].Image, slide.Images[i].Original = image.Name, image.Original
+	}
+	return slide, nil
 }
 
-func copyMedia(questionName string, imageName string, from string, to string) string {
-	parts := strings.Split(imageName, "/")
-	ifn := questionName + "-" + parts[len(parts)-1]
-	ifop := filepath.Join(from, ifn)
-	ifnp := filepath.Join(to, ifn)
+// writeCSV writes all records of the given note type into an Anki import file,
+// or nothing if there aren't any.
+func writeCSV(path string, noteType NoteType, records []Record) error {
+	records = slices.DeleteFunc(slices.Clone(records), func(r Record) bool {
+		return r.NoteType().Name != noteType.Name
+	})
+	if len(records) == 0 {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "#separator:,")
+	fmt.Fprintln(f, "#notetype:"+noteType.Name)
+	fmt.Fprintln(f, "#columns:"+strings.Join(noteType.Columns, ",")+",Tags")
+	fmt.Fprintf(f, "#tags column:%d\n", len(noteType.Columns)+1)
+
+	w := csv.NewWriter(f)
+	for _, record := range records {
+		w.Write(append(record.Record(), strings.Join(record.Tags(), " ")))
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// translatedTexts returns the texts of a record which are asked besides its
+// text, e.g. the options or statements.
+func translatedTexts(record Record) []string {
+	var texts []string
+	switch r := record.(type) {
+	case *SingleChoice:
+		texts = r.Options
+	case *MultipleChoice:
+		texts = r.Options
+	case *BuildList:
+		texts = r.Options
+	case *LiveScreen:
+		for _, opts := range r.Options {
+			texts = append(texts, strings.Join(opts, " / "))
+		}
+	case *SelectPlaceMup:
+		for _, opts := range r.Options {
+			texts = append(texts, strings.Join(opts, " / "))
+		}
+	case *ContentTable:
+		for _, statements := range r.Statements {
+			texts = append(texts, statements...)
+		}
+	case *DragDrop:
+		texts = append(texts, r.Options...)
+		for _, a := range r.Answers {
+			texts = append(texts, a.Target)
+		}
+	}
+	return slices.DeleteFunc(slices.Clone(texts), func(text string) bool {
+		return text == ""
+	})
+}
+
+// translate puts the texts of each record in the second language on the back
+// of the corresponding record. Both were converted from the same dump, so
+// they're in the same order.
+func translate(records []Record, translations []Record) error {
+	if len(records) != len(translations) {
+		return fmt.Errorf(
+			"got %d questions in the second language instead of %d",
+			len(translations),
+			len(records),
+		)
+	}
+
+	for i, record := range records {
+		noteType := record.NoteType()
+		if translations[i].NoteType().Name != noteType.Name {
+			return fmt.Errorf("question %s differs in the second language", record.Record()[0])
+		}
+
+		var b strings.Builder
+		b.WriteString(`<div class="translation">`)
+
+		values := translations[i].Record()
+		if j := slices.Index(noteType.Columns, "Text"); values[j] != "" {
+			b.WriteString(`<div class="text">` + values[j] + `</div>`)
+		}
+		if texts := translatedTexts(translations[i]); len(texts) > 0 {
+			b.WriteString("<ul>")
+			for _, text := range texts {
+				b.WriteString("<li>" + text + "</li>")
+			}
+			b.WriteString("</ul>")
+		}
+		if j := slices.Index(noteType.Columns, "Explanation"); values[j] != "" {
+			b.WriteString(`<div class="explanation">` + values[j] + `</div>`)
+		}
+
+		b.WriteString("</div>")
+		record.SetTranslation(b.String())
+	}
+	return nil
+}
 
-	buf, _ := os.ReadFile(ifop)
-	os.WriteFile(ifnp, buf, 0o644)
+type convertOptions struct {
+	// Media enables processing images if set.
+	Media *MediaOptions
+	// Placeholders substitutes missing images by a placeholder instead of
+	// failing.
+	Placeholders bool
+	// Language selects the TextDB of a language fetched by dump, the IDs of
+	// the records are suffixed by it so that the decks don't collide.
+	Language string
+}
 
-	return ifn
+type produceOptions struct {
+	convertOptions
+	// MinCoverage is the ratio of converted questions below which produce
+	// fails, e.g. 0.9 for 90%.
+	MinCoverage float64
+	// Strict fails if any text couldn't be resolved.
+	Strict bool
+	// Languages to produce a deck for each, or a single bilingual one.
+	Languages []string
+	Bilingual bool
+	// Format is the format of the written files: csv, markdown, json, jsonl,
+	// gift, moodle or qti.
+	Format string
+	// PerQuestion writes a Markdown file per question instead of one per
+	// skill group.
+	PerQuestion bool
 }
 
-func produce(testName string) error {
+// convert reads the dump of a test and turns its questions into records,
+// copying their media into the given folder.
+func convert(testName string, media string, opts convertOptions) ([]Record, *CoverageReport, error) {
 	src := filepath.Join("out", "dump", testName)
 
 	if _, err := os.Stat(src); os.IsNotExist(err) {
-		return fmt.Errorf("'%s' was not found", testName)
+		return nil, nil, fmt.Errorf("'%s' was not found", testName)
 	} else if err != nil {
-		return fmt.Errorf("accessing '%s': %v", testName, err)
+		return nil, nil, fmt.Errorf("accessing '%s': %v", testName, err)
 	}
 
-	media := filepath.Join("out", "collection.media")
 	os.MkdirAll(media, 0o755)
 
 	var groups []SkillGroup
 	if err := readJSON(filepath.Join(src, "skillGroups.json"), &groups); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	var textDB TextDB
-	if err := readJSON(filepath.Join(src, "textdb.json"), &textDB); err != nil {
-		return err
+	if err := readJSON(filepath.Join(src, textDBFile(opts.Language)), &textDB); err != nil {
+		return nil, nil, err
+	}
+
+	store, err := OpenMediaStore(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	store.Process = opts.Media
+
+	copier := &mediaCopier{
+		store:        store,
+		to:           media,
+		placeholders: opts.Placeholders,
 	}
 
 	var records []Record
+	report := NewCoverageReport(testName)
+	caseStudies := make(map[string]*CaseStudy)
 
 	for _, group := range groups {
 		for i := 0; i < len(group.Questions); i++ {
@@ -77,132 +239,341 @@ func produce(testName string) error {
 
 			var question Question
 			if err := readJSON(qfp, &question); err != nil {
-				return err
+				return nil, nil, err
 			}
 
-			images := question.Images()
-			for i := range images {
-				images[i].Name = copyMedia(
-					qfn,
-					images[i].Name,
-					filepath.Join(src, "images"),
-					media,
-				)
-			}
+			textDB := textDB.For(qfn)
 
-			parts := strings.Split(question.StartSlide.Value, "/")
-			sfp := filepath.Join(src, "slides", parts[1]+".json")
+			exhibits := question.Exhibits()
+			exhibits.Resolve(textDB)
+			exhibits.Walk(func(e *Exhibit) {
+				if e.Kind == ExhibitImage {
+					copier.Copy(qfn, &e.Image)
+				}
+			})
 
-			var slide QuestionSlide
-			if err := readJSON(sfp, &slide); err != nil {
-				return err
-			} else if slide.View.Image != "" {
-				slide.View.Image = copyMedia(
-					qfn,
-					slide.View.Image,
-					filepath.Join(src, "images"),
-					media,
-				)
-			}
-			for i := range slide.Images {
-				slide.Images[i].Image = copyMedia(
-					qfn,
-					slide.Images[i].Image,
-					filepath.Join(src, "images"),
-					media,
-				)
+			var slides QuestionSlides
+			for _, slideName := range question.SlideNames() {
+				slide, err := readSlide(src, copier, qfn, slideName)
+				if err != nil {
+					return nil, nil, err
+				}
+				slides = append(slides, slide)
 			}
 
 			_, id, _ := strings.Cut(groupQuestion.Name, "_")
+			if opts.Language != "" {
+				id += "-" + strings.ToLower(opts.Language)
+			}
 
 			if groupQuestion.Type == "caseStudyQuestion" {
 				groupQuestion.Type = skillGroup2questionType[question.Type.Value]
 			}
 
+			var record Record
+
 			switch groupQuestion.Type {
 			case "caseStudy":
-				for _, opt := range slide.CaseStudy[0].Options {
-					if opt.CSContext != "" {
-						group.Questions = append(group.Questions, SkillGroupQuestion{
-							Name: groupQuestion.Name + "_" + opt.CSContext,
-							Type: "caseStudyQuestion",
-						})
-					}
+				cs, questions, err := readCaseStudy(src, copier, textDB, id, groupQuestion.Name, slides)
+				if err != nil {
+					log.Printf("Skipping %s: %v\n", qfn, err)
+					report.Fail(group, groupQuestion.Type, id, err)
+					continue
 				}
+				for _, name := range questions {
+					group.Questions = append(group.Questions, SkillGroupQuestion{
+						Name: name,
+						Type: "caseStudyQuestion",
+					})
+					caseStudies[name] = cs
+				}
+				continue
 			case "singleChoice":
-				records = append(records, NewSingleChoice(
+				record = NewSingleChoice(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
+					exhibits,
+					slides,
+				)
 			case "multipleChoice":
-				records = append(records, NewMultipleChoice(
+				record = NewMultipleChoice(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
+					exhibits,
+					slides,
+				)
 			case "liveScreen":
-				records = append(records, NewLiveScreen(
+				record = NewLiveScreen(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
+					exhibits,
+					slides,
+				)
 			case "contentTable":
-				records = append(records, NewContentTable(
+				record = NewContentTable(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
+					exhibits,
+					slides,
+				)
 			case "buildList":
 				fallthrough
 			case "buildListReorder":
-				records = append(records, NewBuildList(
+				record = NewBuildList(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
+					exhibits,
+					slides,
+				)
+			case "dragAndDrop":
+				fallthrough
+			case "matching":
+				record = NewDragDrop(
+					id,
+					textDB,
+					group,
+					question,
+					exhibits,
+					slides,
+				)
+			case "hotspot":
+				fallthrough
+			case "hotArea":
+				hs, err := NewHotSpot(
+					id,
+					textDB,
+					group,
+					question,
+					exhibits,
+					slides,
+				)
+				if err == nil {
+					err = hs.Annotate(media)
+				}
+				if err != nil {
+					log.Printf("Skipping %s: %v\n", qfn, err)
+					report.Fail(group, groupQuestion.Type, id, err)
+					continue
+				}
+				record = hs
 			case "selectPlaceMup":
-				records = append(records, NewSelectPlaceMup(
+				sp, err := NewSelectPlaceMup(
 					id,
 					textDB,
 					group,
 					question,
-					images,
-					slide,
-				))
-			default:
+					exhibits,
+					slides,
+				)
+				if err != nil {
+					log.Printf("Skipping %s: %v\n", qfn, err)
+					report.Fail(group, groupQuestion.Type, id, err)
+					continue
+				}
+				record = sp
+			}
+
+			if record == nil {
 				log.Println("Skipping...")
+				report.Skip(group, groupQuestion.Type, id)
+				continue
 			}
+			if cs := caseStudies[groupQuestion.Name]; cs != nil {
+				record.SetCaseStudy(cs)
+			}
+			records = append(records, record)
+			report.Convert(group, groupQuestion.Type)
+			report.AddReferences(group, record.References())
 		}
 	}
+	if err := store.WriteOriginals(media, linkedMedia(records)); err != nil {
+		return nil, nil, err
+	}
+	report.MissingMedia = copier.missing
+	report.UnresolvedTexts = textDB.Misses()
+	return records, report, nil
+}
 
-	f, _ := os.Create(filepath.Join("out", strings.ToLower(testName)+".csv"))
-	defer f.Close()
+// readCaseStudy reads the tabs of a case study and returns it along with the
+// names of its questions.
+func readCaseStudy(
+	src string,
+	copier *mediaCopier,
+	textDB TextDB,
+	id string,
+	questionName string,
+	slides QuestionSlides,
+) (*CaseStudy, []string, error) {
+	opts, err := slides.Merged().CaseStudyOptions()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	fmt.Fprintln(f, "#separator:,")
-	fmt.Fprintln(f, "#notetype:MeasureUpCard")
-	fmt.Fprintln(f, "#columns:"+strings.Join(CSVColumns(), ","))
+	_, qfn, _ := strings.Cut(questionName, "/")
+	cs := &CaseStudy{ID: id}
+	var questions []string
+	for _, opt := range opts {
+		if opt.CSContext != "" {
+			questions = append(questions, questionName+"_"+opt.CSContext)
+		} else if opt.View != "" {
+			tab, err := readSlide(src, copier, qfn, opt.View)
+			if err != nil {
+				return nil, nil, fmt.Errorf("reading tab %s: %v", opt.View, err)
+			}
+			cs.Tabs = append(cs.Tabs, NewCaseStudyTab(textDB, opt.Label, tab))
+		}
+	}
+	return cs, questions, nil
+}
 
-	w := csv.NewWriter(f)
-	for _, record := range records {
-		w.Write(record.Record())
+func produce(testName string, opts produceOptions) error {
+	switch opts.Format {
+	case "csv", "markdown", "json", "jsonl", "gift", "moodle", "qti":
+	default:
+		return fmt.Errorf("unknown format '%s'", opts.Format)
 	}
-	w.Flush()
 
+	if err := checkLanguages(testName, opts.Languages); err != nil {
+		return err
+	}
+
+	if opts.Bilingual {
+		if len(opts.Languages) != 2 {
+			return fmt.Errorf("bilingual cards need exactly two languages")
+		}
+		return produceDeck(testName, opts.Languages[0], opts.Languages[1], opts)
+	} else if len(opts.Languages) == 0 {
+		return produceDeck(testName, "", "", opts)
+	}
+
+	for _, language := range opts.Languages {
+		if err := produceDeck(testName, language, "", opts); err != nil {
+			return fmt.Errorf("%s: %v", language, err)
+		}
+	}
+	return nil
+}
+
+// produceDeck writes the import files of a test in one language, with the
+// texts of the second one on the back of the cards if given.
+func produceDeck(testName string, language string, second string, opts produceOptions) error {
+	name := testName
+	if language != "" {
+		name += "-" + language
+	}
+
+	media := filepath.Join("out", "collection.media")
+	dir := filepath.Join("out", "markdown", strings.ToLower(name))
+	if opts.Format == "markdown" {
+		media = filepath.Join(dir, "media")
+	}
+
+	convertOpts := opts.convertOptions
+	convertOpts.Language = language
+	records, report, err := convert(testName, media, convertOpts)
+	if err != nil {
+		return err
+	}
+
+	if second != "" {
+		convertOpts.Language = second
+		translations, secondReport, err := convert(testName, media, convertOpts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", second, err)
+		}
+		if err := translate(records, translations); err != nil {
+			return err
+		}
+		report.UnresolvedTexts = append(report.UnresolvedTexts, secondReport.UnresolvedTexts...)
+	}
+
+	switch opts.Format {
+	case "csv":
+		for _, noteType := range NoteTypes {
+			err := writeCSV(
+				filepath.Join("out", strings.ToLower(name+"-"+noteType.Name)+".csv"),
+				noteType,
+				records,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	case "markdown":
+		if err := writeMarkdown(dir, testName, records, opts.PerQuestion); err != nil {
+			return err
+		}
+	case "json", "jsonl":
+		err := writeExport(
+			filepath.Join("out", strings.ToLower(name)+"."+opts.Format),
+			testName,
+			language,
+			records,
+			filepath.Base(media),
+			opts.Format == "jsonl",
+		)
+		if err != nil {
+			return err
+		}
+	case "gift":
+		err := writeGIFT(filepath.Join("out", strings.ToLower(name)+".gift.txt"), testName, records)
+		if err != nil {
+			return err
+		}
+	case "moodle":
+		err := writeMoodleXML(
+			filepath.Join("out", strings.ToLower(name)+".moodle.xml"),
+			testName,
+			records,
+			media,
+		)
+		if err != nil {
+			return err
+		}
+	case "qti":
+		err := writeQTI(
+			filepath.Join("out", strings.ToLower(name)+".qti.zip"),
+			testName,
+			records,
+			media,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	report.Print(os.Stdout)
+	err = report.WriteJSON(filepath.Join("out", strings.ToLower(name)+".report.json"))
+	if err != nil {
+		return err
+	}
+	err = report.WriteReadingList(filepath.Join("out", strings.ToLower(name)+".references.md"))
+	if err != nil {
+		return err
+	}
+
+	if len(report.UnresolvedTexts) > 0 && opts.Strict {
+		return fmt.Errorf("%d texts couldn't be resolved", len(report.UnresolvedTexts))
+	}
+	if len(report.MissingMedia) > 0 && !opts.Placeholders {
+		return fmt.Errorf("%d media files are missing", len(report.MissingMedia))
+	}
+	if report.Coverage < opts.MinCoverage {
+		return fmt.Errorf(
+			"coverage of %.1f%% is below the required %.1f%%",
+			report.Coverage*100,
+			opts.MinCoverage*100,
+		)
+	}
 	return nil
 }
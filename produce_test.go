@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// caseStudyDump adds a case study with an overview tab and one question to
+// the test dump.
+var caseStudyDump = map[string]string{
+	"skillGroups.json": `[{"ID": 1, "Name": "Group One", "Questions": [
+		{"Name": "MUP/Q_1", "question_type": "singleChoice"},
+		{"Name": "MUP/Q_8", "question_type": "caseStudy"}
+	]}]`,
+	"textdb.json": `{
+		"s1": "Which one?", "e1": "Because.", "o1": "A", "o2": "B",
+		"l1": "Overview", "l2": "Question", "cst1": "Contoso has two sites.",
+		"s8a": "Which site?", "o81": "First", "o82": "Second"
+	}`,
+	"questions/Q_8.json": `{"Stem": {"Value": ""}, "StartSlide": {"Value": "views/Q_8_s"}}`,
+	"slides/Q_8_s.json": `{"CaseStudy": [{"Options": [
+		{"Label": "$$l1", "View": "views/Q_8_overview"},
+		{"Label": "$$l2", "CSContext": "ctxA"}
+	]}]}`,
+	"slides/Q_8_overview.json": `{"Texts": [{"ID": "t", "Value": "$$cst1"}]}`,
+	"questions/Q_8_ctxA.json": `{
+		"Stem": {"Value": "$$s8a"},
+		"Type": {"Value": "singleChoice"},
+		"Models": [{"Model": "m", "Correct": "rb1"}],
+		"StartSlide": {"Value": "views/Q_8_ctxA_s"}
+	}`,
+	"slides/Q_8_ctxA_s.json": `{"RadioButtons": [
+		{"ID": "rb1", "Value": "$$o81"},
+		{"ID": "rb2", "Value": "$$o82"}
+	]}`,
+}
+
+func TestConvertCaseStudy(t *testing.T) {
+	writeTestDump(t, caseStudyDump)
+
+	records, report, err := convert("t1", "out/collection.media", convertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || report.Converted != 2 {
+		t.Fatalf("got %d records, %d converted, want 2", len(records), report.Converted)
+	}
+
+	sc, ok := records[1].(*SingleChoice)
+	if !ok || sc.ID != "8_ctxA" {
+		t.Fatalf("second record = %#v, want the case study's question", records[1])
+	}
+	cs := sc.CaseStudy
+	if cs == nil || len(cs.Tabs) != 1 || cs.Tabs[0].Label != "Overview" || cs.Tabs[0].Text != "Contoso has two sites." {
+		t.Fatalf("case study = %+v, want the overview tab", cs)
+	}
+	if !strings.Contains(sc.CaseStudyHTML(), "Contoso has two sites.") {
+		t.Errorf("case study isn't rendered: %s", sc.CaseStudyHTML())
+	}
+	if tags := sc.Tags(); !strings.Contains(strings.Join(tags, " "), cs.Tag()) {
+		t.Errorf("tags = %v, want %s", tags, cs.Tag())
+	}
+	if records[0].(*SingleChoice).CaseStudy != nil {
+		t.Error("the question outside of the case study got it attached")
+	}
+}
+
+func TestConvertMalformedCaseStudy(t *testing.T) {
+	for name, slide := range map[string]string{
+		"no blocks":    `{"CaseStudy": []}`,
+		"no questions": `{"CaseStudy": [{"Options": [{"Label": "$$l1", "View": "views/Q_8_overview"}]}]}`,
+		"missing tab":  `{"CaseStudy": [{"Options": [{"Label": "$$l1", "View": "views/gone"}, {"CSContext": "ctxA"}]}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dump := map[string]string{"slides/Q_8_s.json": slide}
+			for k, v := range caseStudyDump {
+				if _, ok := dump[k]; !ok {
+					dump[k] = v
+				}
+			}
+			writeTestDump(t, dump)
+
+			records, report, err := convert("t1", "out/collection.media", convertOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Errorf("got %d records, want only the question outside of the case study", len(records))
+			}
+			if len(report.Errors) != 1 || report.Errors[0].Question != "8" {
+				t.Errorf("errors = %v, want one for the case study", report.Errors)
+			}
+			if tc := report.Types["caseStudy"]; tc == nil || tc.Skipped != 1 {
+				t.Errorf("caseStudy coverage = %+v, want 1 skipped", tc)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	records := []Record{
+		&ContentTable{ID: "1", Text: "Yes or no?", Statements: [][]string{{"One", "Two"}}, Answers: [][]bool{{true, false}}},
+		&BuildList{ID: "2", Text: "Order", Options: []string{"First", "Second"}},
+		&LiveScreen{ID: "3", Text: "Complete", Options: [][]string{{"A", "B"}, {"C"}}},
+		&DragDrop{ID: "4", Options: []string{"Source"}, Answers: []DragDropTarget{{Target: "Target"}}},
+		&SingleChoice{ID: "5", Text: "Which?", Options: []string{"A", "B"}, Explanation: "Because."},
+	}
+	translations := []Record{
+		&ContentTable{ID: "1", Text: "Ja oder nein?", Statements: [][]string{{"Eins", "Zwei"}}, Answers: [][]bool{{true, false}}},
+		&BuildList{ID: "2", Text: "Ordnen", Options: []string{"Erstens", "Zweitens"}},
+		&LiveScreen{ID: "3", Text: "Vervollständigen", Options: [][]string{{"A", "B"}, {"C"}}},
+		&DragDrop{ID: "4", Options: []string{"Quelle"}, Answers: []DragDropTarget{{Target: "Ziel"}}},
+		&SingleChoice{ID: "5", Text: "Welche?", Options: []string{"A", "B"}, Explanation: "Weil."},
+	}
+	if err := translate(records, translations); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`<div class="translation"><div class="text">Ja oder nein?</div><ul><li>Eins</li><li>Zwei</li></ul></div>`,
+		`<div class="translation"><div class="text">Ordnen</div><ul><li>Erstens</li><li>Zweitens</li></ul></div>`,
+		`<div class="translation"><div class="text">Vervollständigen</div><ul><li>A / B</li><li>C</li></ul></div>`,
+		`<div class="translation"><ul><li>Quelle</li><li>Ziel</li></ul></div>`,
+		`<div class="translation"><div class="text">Welche?</div><ul><li>A</li><li>B</li></ul>` +
+			`<div class="explanation">Weil.</div></div>`,
+	}
+	for i, record := range records {
+		j := slices.Index(record.NoteType().Columns, "Translation")
+		if got := record.Record()[j]; got != want[i] {
+			t.Errorf("translation of %s = %s\nwant %s", record.Record()[0], got, want[i])
+		}
+	}
+
+	if err := translate(records[:1], translations[1:2]); err == nil {
+		t.Error("a question of another type was taken as the translation")
+	}
+}
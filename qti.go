@@ -0,0 +1,595 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var qtiUnsafeID = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// qtiTags maps the tags of a text to the ones QTI's subset of XHTML knows,
+// other tags are dropped while their content is kept.
+var qtiTags = map[string]string{
+	"a": "a", "img": "img", "b": "b", "strong": "strong", "i": "i", "em": "em",
+	"u": "span", "s": "span", "sub": "sub", "sup": "sup", "code": "code",
+	"kbd": "kbd", "samp": "samp", "var": "var", "p": "p", "br": "br", "hr": "hr",
+	"div": "div", "span": "span", "pre": "pre", "blockquote": "div",
+	"h1": "h1", "h2": "h2", "h3": "h3", "h4": "h4", "h5": "h5", "h6": "h6",
+	"ul": "ul", "ol": "ol", "li": "li", "dl": "dl", "dt": "dt", "dd": "dd",
+	"table": "table", "thead": "thead", "tbody": "tbody", "tfoot": "tfoot",
+	"tr": "tr", "th": "th", "td": "td", "details": "div", "summary": "div",
+}
+
+// qtiAttrs are the attributes kept on any tag.
+var qtiAttrs = []string{"class", "href", "src", "alt", "width", "height", "colspan", "rowspan"}
+
+// qtiBlocks are the tags of blocks, which QTI doesn't allow inside of
+// qtiInlineOnly ones.
+var qtiBlocks = map[string]bool{
+	"div": true, "p": true, "pre": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "ul": true, "ol": true, "dl": true,
+	"table": true, "hr": true,
+}
+
+var qtiInlineOnly = map[string]bool{
+	"p": true, "pre": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "dt": true, "a": true, "span": true, "b": true,
+	"strong": true, "i": true, "em": true, "sub": true, "sup": true,
+	"code": true, "kbd": true, "samp": true, "var": true,
+}
+
+// qtiNotInPre are the tags QTI doesn't allow inside of a pre.
+var qtiNotInPre = map[string]bool{"img": true, "sub": true, "sup": true}
+
+// qtiOpenTag is an element left open by xhtml, tag being the one of the HTML
+// it was converted from.
+type qtiOpenTag struct {
+	tag  string
+	name string
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
+
+// qtiID turns s into an identifier which is valid in QTI and the manifest.
+func qtiID(prefix string, s string) string {
+	return prefix + "-" + strings.Trim(qtiUnsafeID.ReplaceAllString(s, "-"), "-")
+}
+
+// qtiItemWriter converts the HTML of a record into XHTML and collects the
+// media it refers to, which is put into the media folder of the package.
+type qtiItemWriter struct {
+	files []string
+}
+
+// mediaLink returns the link to an image of the media folder inside the package
+// and collects the image.
+func (w *qtiItemWriter) mediaLink(link string) string {
+	if strings.ContainsAny(link, ":/") || link == "" {
+		return link
+	}
+	if !slices.Contains(w.files, link) {
+		w.files = append(w.files, link)
+	}
+	return "media/" + link
+}
+
+// xhtml converts a sanitized HTML text into XHTML as QTI allows it: blocks
+// close the paragraphs and inline elements they're in, rows of a table are
+// put into a tbody, links need a target and images an alt text.
+func (w *qtiItemWriter) xhtml(s string) string {
+	var b strings.Builder
+	var open []qtiOpenTag
+
+	closeTo := func(i int) {
+		for len(open) > i {
+			b.WriteString("</" + open[len(open)-1].name + ">")
+			open = open[:len(open)-1]
+		}
+	}
+	inPre := func() bool {
+		return slices.ContainsFunc(open, func(t qtiOpenTag) bool { return t.name == "pre" })
+	}
+
+	s = htmlComment.ReplaceAllString(s, "")
+	pos := 0
+	for _, m := range htmlTag.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(xmlEscape(html.UnescapeString(s[pos:m[0]])))
+		pos = m[1]
+
+		tag := strings.ToLower(s[m[4]:m[5]])
+		name, ok := qtiTags[tag]
+		if !ok {
+			continue
+		} else if m[3] > m[2] {
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].tag == tag {
+					closeTo(i)
+					break
+				}
+			}
+			continue
+		} else if qtiNotInPre[name] && inPre() {
+			continue
+		}
+
+		attrs := htmlAttrs(s[m[6]:m[7]])
+		switch name {
+		case "a":
+			if attrs["href"] == "" {
+				name = "span"
+			}
+		case "img":
+			if attrs["src"] == "" {
+				continue
+			}
+			if _, ok := attrs["alt"]; !ok {
+				attrs["alt"] = ""
+			}
+		case "tr":
+			if len(open) > 0 && open[len(open)-1].name == "table" {
+				b.WriteString("<tbody>")
+				open = append(open, qtiOpenTag{"", "tbody"})
+			}
+		}
+		if qtiBlocks[name] {
+			if i := slices.IndexFunc(open, func(t qtiOpenTag) bool {
+				return qtiInlineOnly[t.name]
+			}); i >= 0 {
+				closeTo(i)
+			}
+		}
+
+		b.WriteString("<" + name)
+		for _, attr := range qtiAttrs {
+			value, ok := attrs[attr]
+			if !ok {
+				continue
+			}
+			if name == "img" && attr == "src" {
+				value = w.mediaLink(value)
+			}
+			fmt.Fprintf(&b, ` %s="%s"`, attr, xmlEscape(value))
+		}
+		if voidTags[name] {
+			b.WriteString("/>")
+		} else {
+			b.WriteString(">")
+			open = append(open, qtiOpenTag{tag, name})
+		}
+	}
+	b.WriteString(xmlEscape(html.UnescapeString(s[pos:])))
+	closeTo(0)
+	return b.String()
+}
+
+// wellFormed fails if buf isn't well-formed XML.
+func wellFormed(buf []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(buf))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// block wraps a text into a div, as the body of an item only contains blocks.
+func (w *qtiItemWriter) block(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<div>" + w.xhtml(s) + "</div>"
+}
+
+// plain returns the text of an option without any markup, as an inline
+// choice can't contain any.
+func plain(s string) string {
+	return xmlEscape(strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(s, ""))))
+}
+
+type qtiInner struct {
+	XML string `xml:",innerxml"`
+}
+
+type qtiResponseDeclaration struct {
+	Identifier  string              `xml:"identifier,attr"`
+	Cardinality string              `xml:"cardinality,attr"`
+	BaseType    string              `xml:"baseType,attr"`
+	Correct     *qtiCorrectResponse `xml:"correctResponse"`
+}
+
+type qtiCorrectResponse struct {
+	Values []string `xml:"value"`
+}
+
+type qtiOutcomeDeclaration struct {
+	Identifier  string              `xml:"identifier,attr"`
+	Cardinality string              `xml:"cardinality,attr"`
+	BaseType    string              `xml:"baseType,attr"`
+	Default     *qtiCorrectResponse `xml:"defaultValue"`
+}
+
+type qtiVariable struct {
+	Identifier string `xml:"identifier,attr"`
+}
+
+type qtiMatch struct {
+	Variable qtiVariable `xml:"variable"`
+	Correct  qtiVariable `xml:"correct"`
+}
+
+type qtiBaseValue struct {
+	BaseType string `xml:"baseType,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type qtiSetOutcomeValue struct {
+	Identifier string       `xml:"identifier,attr"`
+	Value      qtiBaseValue `xml:"baseValue"`
+}
+
+type qtiResponseIf struct {
+	Matches []qtiMatch         `xml:"and>match"`
+	Score   qtiSetOutcomeValue `xml:"setOutcomeValue"`
+}
+
+type qtiResponseElse struct {
+	Score qtiSetOutcomeValue `xml:"setOutcomeValue"`
+}
+
+type qtiResponseProcessing struct {
+	If       qtiResponseIf       `xml:"responseCondition>responseIf"`
+	Else     qtiResponseElse     `xml:"responseCondition>responseElse"`
+	Feedback *qtiSetOutcomeValue `xml:"setOutcomeValue"`
+}
+
+type qtiModalFeedback struct {
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	Title             string `xml:"title,attr,omitempty"`
+	Content           string `xml:",innerxml"`
+}
+
+type qtiItem struct {
+	XMLName        xml.Name                 `xml:"assessmentItem"`
+	Xmlns          string                   `xml:"xmlns,attr"`
+	XmlnsXsi       string                   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string                   `xml:"xsi:schemaLocation,attr"`
+	Identifier     string                   `xml:"identifier,attr"`
+	Title          string                   `xml:"title,attr"`
+	Adaptive       bool                     `xml:"adaptive,attr"`
+	TimeDependent  bool                     `xml:"timeDependent,attr"`
+	Responses      []qtiResponseDeclaration `xml:"responseDeclaration"`
+	Outcomes       []qtiOutcomeDeclaration  `xml:"outcomeDeclaration"`
+	Body           qtiInner                 `xml:"itemBody"`
+	Processing     qtiResponseProcessing    `xml:"responseProcessing"`
+	Feedback       []qtiModalFeedback       `xml:"modalFeedback"`
+}
+
+type qtiFile struct {
+	Href string `xml:"href,attr"`
+}
+
+type qtiResource struct {
+	Identifier string    `xml:"identifier,attr"`
+	Type       string    `xml:"type,attr"`
+	Href       string    `xml:"href,attr"`
+	Files      []qtiFile `xml:"file"`
+}
+
+type qtiManifest struct {
+	XMLName        xml.Name      `xml:"manifest"`
+	Xmlns          string        `xml:"xmlns,attr"`
+	XmlnsXsi       string        `xml:"xmlns:xsi,attr"`
+	SchemaLocation string        `xml:"xsi:schemaLocation,attr"`
+	Identifier     string        `xml:"identifier,attr"`
+	Schema         string        `xml:"metadata>schema"`
+	SchemaVersion  string        `xml:"metadata>schemaversion"`
+	Organizations  struct{}      `xml:"organizations"`
+	Resources      []qtiResource `xml:"resources>resource"`
+}
+
+// choiceIDs returns the identifiers of the options at the given 1-based
+// positions.
+func choiceIDs(prefix string, positions []int, n int) []string {
+	var ids []string
+	for _, i := range positions {
+		if i > 0 && i <= n {
+			ids = append(ids, fmt.Sprintf("%s%d", prefix, i))
+		}
+	}
+	return ids
+}
+
+// inlineChoice returns an inline choice interaction for a dropdown, answer
+// being the 0-based index of its correct option.
+func (item *qtiItem) inlineChoice(options []string, answer int) string {
+	id := fmt.Sprintf("RESPONSE%d", len(item.Responses)+1)
+	decl := qtiResponseDeclaration{Identifier: id, Cardinality: "single", BaseType: "identifier"}
+	if answer >= 0 && answer < len(options) {
+		decl.Correct = &qtiCorrectResponse{[]string{fmt.Sprintf("C%d", answer+1)}}
+	}
+	item.Responses = append(item.Responses, decl)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<inlineChoiceInteraction responseIdentifier="%s" shuffle="false">`, id)
+	for i, opt := range options {
+		fmt.Fprintf(&b, `<inlineChoice identifier="C%d">%s</inlineChoice>`, i+1, plain(opt))
+	}
+	b.WriteString(`</inlineChoiceInteraction>`)
+	return b.String()
+}
+
+// qtiItemOf maps a record to an assessment item: choices to a choice
+// interaction, build lists to an order interaction, live screens and select
+// place ups to inline choices, content tables and drag and drops to a match
+// interaction and hotspots to a hotspot interaction.
+func qtiItemOf(testName string, record Record, w *qtiItemWriter) qtiItem {
+	parts := partsOf(record)
+
+	item := qtiItem{
+		Xmlns:          "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd",
+		Identifier:     qtiID("item", strings.ToLower(testName)+"-"+parts.ID),
+		Title:          parts.ID,
+		Outcomes: []qtiOutcomeDeclaration{{
+			Identifier:  "SCORE",
+			Cardinality: "single",
+			BaseType:    "float",
+			Default:     &qtiCorrectResponse{[]string{"0"}},
+		}},
+	}
+
+	var body strings.Builder
+	body.WriteString(w.block(parts.Text))
+	body.WriteString(w.block(parts.Exhibits.HTML()))
+	body.WriteString(w.block(parts.Context.CaseStudyHTML()))
+
+	respond := func(cardinality string, baseType string, correct []string) {
+		decl := qtiResponseDeclaration{Identifier: "RESPONSE", Cardinality: cardinality, BaseType: baseType}
+		if len(correct) > 0 {
+			decl.Correct = &qtiCorrectResponse{correct}
+		}
+		item.Responses = append(item.Responses, decl)
+	}
+	choices := func(tag string, prefix string, options []string) {
+		for i, opt := range options {
+			fmt.Fprintf(&body, `<%s identifier="%s%d">%s</%s>`, tag, prefix, i+1, w.xhtml(opt), tag)
+		}
+	}
+
+	switch r := record.(type) {
+	case *SingleChoice:
+		respond("single", "identifier", choiceIDs("C", []int{r.Answer}, len(r.Options)))
+		body.WriteString(`<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="1">`)
+		choices("simpleChoice", "C", r.Options)
+		body.WriteString(`</choiceInteraction>`)
+	case *MultipleChoice:
+		respond("multiple", "identifier", choiceIDs("C", r.Answers, len(r.Options)))
+		fmt.Fprintf(&body, `<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="%d">`, len(r.Answers))
+		choices("simpleChoice", "C", r.Options)
+		body.WriteString(`</choiceInteraction>`)
+	case *BuildList:
+		respond("ordered", "identifier", choiceIDs("C", r.Answers, len(r.Options)))
+		if len(r.Options) > len(r.Answers) {
+			// Only some of the options belong to the order, the rest are
+			// distractors.
+			fmt.Fprintf(&body, `<orderInteraction responseIdentifier="RESPONSE" shuffle="true" minChoices="%d" maxChoices="%d">`,
+				len(r.Answers), len(r.Answers))
+		} else {
+			body.WriteString(`<orderInteraction responseIdentifier="RESPONSE" shuffle="true">`)
+		}
+		choices("simpleChoice", "C", r.Options)
+		body.WriteString(`</orderInteraction>`)
+	case *LiveScreen:
+		body.WriteString(w.block(r.ImageHTML()))
+		n := 0
+		for i, screen := range r.Screens {
+			body.WriteString("<div>")
+			if i > 0 {
+				body.WriteString(w.xhtml(screen.Image.HTML("image")))
+			}
+			body.WriteString("<ol>")
+			for j := 0; j < screen.Dropdowns; j++ {
+				body.WriteString("<li>" + item.inlineChoice(r.Options[n], r.Answers[n]) + "</li>")
+				n++
+			}
+			body.WriteString("</ol></div>")
+		}
+	case *SelectPlaceMup:
+		body.WriteString(w.block(r.ImageHTML()))
+		body.WriteString("<ol>")
+		for i, opts := range r.Options {
+			body.WriteString("<li>" + item.inlineChoice(opts, r.Answers[i]) + "</li>")
+		}
+		body.WriteString("</ol>")
+	case *ContentTable:
+		var statements []string
+		var correct []string
+		for i, stmts := range r.Statements {
+			for j, stmt := range stmts {
+				statements = append(statements, stmt)
+				answer := "NO"
+				if r.Answers[i][j] {
+					answer = "YES"
+				}
+				correct = append(correct, fmt.Sprintf("S%d %s", len(statements), answer))
+			}
+		}
+		respond("multiple", "directedPair", correct)
+		fmt.Fprintf(&body, `<matchInteraction responseIdentifier="RESPONSE" shuffle="false" maxAssociations="%d">`, len(statements))
+		body.WriteString(`<simpleMatchSet>`)
+		for i, stmt := range statements {
+			fmt.Fprintf(&body, `<simpleAssociableChoice identifier="S%d" matchMax="1">%s</simpleAssociableChoice>`, i+1, w.xhtml(stmt))
+		}
+		body.WriteString(`</simpleMatchSet><simpleMatchSet>`)
+		body.WriteString(`<simpleAssociableChoice identifier="YES" matchMax="0">Yes</simpleAssociableChoice>`)
+		body.WriteString(`<simpleAssociableChoice identifier="NO" matchMax="0">No</simpleAssociableChoice>`)
+		body.WriteString(`</simpleMatchSet></matchInteraction>`)
+	case *DragDrop:
+		var correct []string
+		for i, a := range r.Answers {
+			for _, id := range choiceIDs("S", a.Sources, len(r.Options)) {
+				correct = append(correct, fmt.Sprintf("%s T%d", id, i+1))
+			}
+		}
+		respond("multiple", "directedPair", correct)
+		body.WriteString(`<matchInteraction responseIdentifier="RESPONSE" shuffle="true" maxAssociations="0">`)
+		body.WriteString(`<simpleMatchSet>`)
+		for i, opt := range r.Options {
+			fmt.Fprintf(&body, `<simpleAssociableChoice identifier="S%d" matchMax="0">%s</simpleAssociableChoice>`, i+1, w.xhtml(opt))
+		}
+		body.WriteString(`</simpleMatchSet><simpleMatchSet>`)
+		for i, a := range r.Answers {
+			fmt.Fprintf(&body, `<simpleAssociableChoice identifier="T%d" matchMax="0">%s</simpleAssociableChoice>`, i+1, w.xhtml(a.Target))
+		}
+		body.WriteString(`</simpleMatchSet></matchInteraction>`)
+	case *HotSpot:
+		var correct []string
+		for _, i := range r.Answers {
+			correct = append(correct, fmt.Sprintf("H%d", i+1))
+		}
+		respond("multiple", "identifier", correct)
+
+		kind := mime.TypeByExtension(path.Ext(r.Image.Name))
+		if kind == "" {
+			kind = "image/png"
+		}
+		fmt.Fprintf(&body, `<hotspotInteraction responseIdentifier="RESPONSE" maxChoices="%d">`, len(r.Answers))
+		fmt.Fprintf(&body, `<object type="%s" data="%s">%s</object>`,
+			kind, xmlEscape(w.mediaLink(r.Image.Name)), xmlEscape(r.Image.Alt))
+		for i := range r.Regions {
+			region := r.ImageRegion(i)
+			fmt.Fprintf(&body, `<hotspotChoice identifier="H%d" shape="rect" coords="%d,%d,%d,%d"/>`,
+				i+1, region.Min.X, region.Min.Y, region.Max.X, region.Max.Y)
+		}
+		body.WriteString(`</hotspotInteraction>`)
+	}
+	item.Body.XML = body.String()
+
+	item.Processing.If.Score = qtiSetOutcomeValue{"SCORE", qtiBaseValue{"float", "1"}}
+	item.Processing.Else.Score = qtiSetOutcomeValue{"SCORE", qtiBaseValue{"float", "0"}}
+	for _, decl := range item.Responses {
+		item.Processing.If.Matches = append(item.Processing.If.Matches, qtiMatch{
+			Variable: qtiVariable{decl.Identifier},
+			Correct:  qtiVariable{decl.Identifier},
+		})
+	}
+
+	if explanation := parts.Explanation + parts.Context.ReferencesHTML(); explanation != "" {
+		item.Outcomes = append(item.Outcomes, qtiOutcomeDeclaration{
+			Identifier:  "FEEDBACK",
+			Cardinality: "single",
+			BaseType:    "identifier",
+		})
+		item.Processing.Feedback = &qtiSetOutcomeValue{"FEEDBACK", qtiBaseValue{"identifier", "EXPLANATION"}}
+		item.Feedback = append(item.Feedback, qtiModalFeedback{
+			OutcomeIdentifier: "FEEDBACK",
+			ShowHide:          "show",
+			Identifier:        "EXPLANATION",
+			Title:             "Explanation",
+			Content:           w.xhtml(explanation),
+		})
+	}
+	return item
+}
+
+// writeQTI writes the records as a QTI 2.1 content package, a zip file with
+// a manifest, an assessment item per record and the media they refer to.
+func writeQTI(path string, testName string, records []Record, media string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	z := zip.NewWriter(f)
+	manifest := qtiManifest{
+		Xmlns:          "http://www.imsglobal.org/xsd/imscp_v1p1",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.imsglobal.org/xsd/imscp_v1p1 http://www.imsglobal.org/xsd/imscp_v1p1.xsd",
+		Identifier:     qtiID("manifest", strings.ToLower(testName)),
+		Schema:         "QTIv2.1 Package",
+		SchemaVersion:  "1.0.0",
+	}
+
+	var written []string
+	for _, record := range records {
+		w := &qtiItemWriter{}
+		item := qtiItemOf(testName, record, w)
+		href := strings.TrimPrefix(item.Identifier, "item-") + ".xml"
+
+		buf, err := xml.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return err
+		} else if err := wellFormed(buf); err != nil {
+			// The body is put in as it is.
+			log.Printf("Skipping %s for QTI: %v\n", partsOf(record).ID, err)
+			continue
+		}
+		entry, err := z.Create(href)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(append([]byte(xml.Header), buf...)); err != nil {
+			return err
+		}
+
+		resource := qtiResource{
+			Identifier: item.Identifier,
+			Type:       "imsqti_item_xmlv2p1",
+			Href:       href,
+			Files:      []qtiFile{{href}},
+		}
+		for _, name := range w.files {
+			resource.Files = append(resource.Files, qtiFile{"media/" + name})
+			if slices.Contains(written, name) {
+				continue
+			}
+			written = append(written, name)
+
+			buf, err := os.ReadFile(filepath.Join(media, name))
+			if err != nil {
+				return err
+			}
+			entry, err := z.Create("media/" + name)
+			if err != nil {
+				return err
+			}
+			if _, err := entry.Write(buf); err != nil {
+				return err
+			}
+		}
+		manifest.Resources = append(manifest.Resources, resource)
+	}
+
+	buf, err := xml.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	entry, err := z.Create("imsmanifest.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(append([]byte(xml.Header), buf...)); err != nil {
+		return err
+	}
+	return z.Close()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQTIXHTML(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"<p>a &amp; b<br>c</p>", "<p>a &amp; b<br/>c</p>"},
+		{`<p class="x" style="y">t</p>`, `<p class="x">t</p>`},
+		{"<u>u</u> <s>s</s> <font>f</font>", "<span>u</span> <span>s</span> f"},
+		{"<table><tr><td>a</td></tr></table>", "<table><tbody><tr><td>a</td></tr></tbody></table>"},
+		{"<table><thead><tr><th>h</th></tr></thead><tr><td>a</td></tr></table>",
+			"<table><thead><tr><th>h</th></tr></thead><tbody><tr><td>a</td></tr></tbody></table>"},
+		{"<p>one<ul><li>two</li></ul>three</p>", "<p>one</p><ul><li>two</li></ul>three"},
+		{"<b>x<div>y</div></b>", "<b>x</b><div>y</div>"},
+		{"<blockquote><p>q</p></blockquote>", "<div><p>q</p></div>"},
+		{"<a>no target</a> <a href=\"https://a.com\">link</a>", `<span>no target</span> <a href="https://a.com">link</a>`},
+		{`<a href="#ex">anchor</a> <a href="notes.html">page</a>`, `<a href="#ex">anchor</a> <a href="notes.html">page</a>`},
+		{`<img src="a.png">`, `<img src="media/a.png" alt=""/>`},
+		{`<img alt="no source">`, ""},
+		{`<pre>x<sup>2</sup> <img src="a.png"></pre>`, "<pre>x2 </pre>"},
+		{"<b>unclosed <i>tags", "<b>unclosed <i>tags</i></b>"},
+		{"stray</b> close", "stray close"},
+	}
+	for _, tt := range tests {
+		w := &qtiItemWriter{}
+		if got := w.xhtml(tt.html); got != tt.want {
+			t.Errorf("xhtml(%q)\n got %q\nwant %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+// qtiRecords returns a record of every kind, whose images are written into
+// media.
+func qtiRecords(t *testing.T, media string) []Record {
+	t.Helper()
+	for _, name := range []string{"ex.png", "screen.png", "map.png"} {
+		if err := os.WriteFile(filepath.Join(media, name), testPNG(t, 40, 20), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	group := SkillGroup{ID: 1, Name: "One"}
+	exhibits := Exhibits{
+		{Kind: ExhibitImage, Image: QuestionImage{Name: "ex.png", Alt: "Exhibit"}},
+		{Kind: ExhibitTable, Header: []string{"Name"}, Rows: [][]string{{"<p>a<ul><li>b</li></ul></p>"}}},
+		{Kind: ExhibitCode, Text: "if a < b {}", Language: "go"},
+		{Kind: ExhibitTabs, Tabs: []ExhibitTab{{Label: "Tab", Exhibits: Exhibits{{Kind: ExhibitText, Text: "<blockquote>q</blockquote>"}}}}},
+	}
+	sc := &SingleChoice{
+		ID: "1", Group: group, Text: "<p>Which <b>one<div>?</div></p>", Exhibits: exhibits,
+		Options: []string{"<a>a</a>", "b &amp; c"}, Answer: 2,
+		Explanation: `<p>Because, see <a href="#ex">the exhibit</a>.</p>`,
+	}
+	sc.Refs = References{{"Docs", "https://a.com"}}
+	sc.SetCaseStudy(&CaseStudy{ID: "cs1", Tabs: []CaseStudyTab{{Label: "Overview", Text: "<p>Contoso</p>"}}})
+
+	return []Record{
+		sc,
+		&MultipleChoice{ID: "2", Group: group, Text: "Which ones?", Options: []string{"a", "b", "c"}, Answers: []int{1, 3}},
+		&BuildList{ID: "3", Group: group, Text: "Order", Options: []string{"x", "y", "z", "distractor"}, Answers: []int{3, 1, 2}},
+		&BuildList{ID: "4", Group: group, Text: "Order all", Options: []string{"x", "y"}, Answers: []int{2, 1}},
+		&LiveScreen{
+			ID: "5", Group: group, Text: "Complete",
+			Screens: []LiveScreenScreen{
+				{Image: QuestionImage{Name: "screen.png", Alt: "Screen"}, Dropdowns: 1},
+				{Image: QuestionImage{Name: "screen.png", Alt: "Screen"}, Dropdowns: 1},
+			},
+			Options: [][]string{{"<b>a</b>", "b"}, {"c", "d"}}, Answers: []int{0, -1},
+		},
+		&SelectPlaceMup{
+			ID: "6", Group: group, Image: QuestionImage{Name: "map.png", Alt: "Map"},
+			Options: [][]string{{"a", "b"}}, Answers: []int{1},
+		},
+		&ContentTable{ID: "7", Group: group, Statements: [][]string{{"s1", "s2"}}, Answers: [][]bool{{true, false}}},
+		&DragDrop{
+			ID: "8", Group: group, Options: []string{"a", "b", "c"},
+			Answers: []DragDropTarget{{Target: "t1", Sources: []int{1}}, {Target: "t2", Sources: []int{2}}, {Target: "t3"}},
+		},
+		&HotSpot{
+			ID: "9", Group: group, Image: QuestionImage{Name: "screen.png", Alt: "Screen"},
+			Regions: []image.Rectangle{image.Rect(0, 0, 10, 10), image.Rect(20, 5, 30, 10)},
+			Answers: []int{1},
+		},
+	}
+}
+
+func readZip(t *testing.T, path string, dir string) []string {
+	t.Helper()
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+
+	var names []string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, f.Name)
+		if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(out, buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return names
+}
+
+func TestWriteQTI(t *testing.T) {
+	media := t.TempDir()
+	path := filepath.Join(t.TempDir(), "t1.qti.zip")
+	if err := writeQTI(path, "T1", qtiRecords(t, media), media); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	names := readZip(t, path, dir)
+	want := "t1-1.xml, media/ex.png, t1-2.xml, t1-3.xml, t1-4.xml, t1-5.xml, media/screen.png, " +
+		"t1-6.xml, media/map.png, t1-7.xml, t1-8.xml, t1-9.xml, imsmanifest.xml"
+	if got := strings.Join(names, ", "); got != want {
+		t.Errorf("files = %s\nwant %s", got, want)
+	}
+
+	for _, name := range names {
+		if filepath.Ext(name) != ".xml" {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wellFormed(buf); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	order, err := os.ReadFile(filepath.Join(dir, "t1-3.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(order), `<orderInteraction responseIdentifier="RESPONSE" shuffle="true" minChoices="3" maxChoices="3">`) {
+		t.Errorf("order with a distractor doesn't limit the choices:\n%s", order)
+	}
+	order, err = os.ReadFile(filepath.Join(dir, "t1-4.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(order), "Choices=") {
+		t.Errorf("order of all options limits the choices:\n%s", order)
+	}
+}
+
+// qtiSchemas are the official schemas of QTI 2.1 and IMS CP 1.1, which are
+// bundled in testdata/qti, by the URL they're published at.
+var qtiSchemas = map[string]string{
+	"imsqti_v2p1.xsd": "http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd",
+	"imscp_v1p1.xsd":  "http://www.imsglobal.org/xsd/imscp_v1p1.xsd",
+}
+
+// skipUnlessCI skips a test whose requirements are missing, unless it runs in
+// CI, where they must be there.
+func skipUnlessCI(t *testing.T, format string, args ...interface{}) {
+	t.Helper()
+	if os.Getenv("CI") != "" {
+		t.Fatalf(format, args...)
+	}
+	t.Skipf(format, args...)
+}
+
+// TestQTISchemas validates a written package against the official schemas.
+func TestQTISchemas(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		skipUnlessCI(t, "xmllint is needed to validate against the schemas")
+	}
+	schemas, err := filepath.Abs(filepath.Join("testdata", "qti"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, url := range qtiSchemas {
+		if _, err := os.Stat(filepath.Join(schemas, name)); err != nil {
+			skipUnlessCI(t, "%s isn't bundled, download it from %s into testdata/qti", name, url)
+		}
+	}
+
+	media := t.TempDir()
+	path := filepath.Join(t.TempDir(), "t1.qti.zip")
+	if err := writeQTI(path, "T1", qtiRecords(t, media), media); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	names := readZip(t, path, dir)
+
+	validate := func(schema string, file string) error {
+		cmd := exec.Command(xmllint, "--noout", "--schema", filepath.Join(schemas, schema), file)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%v\n%s", err, out)
+		}
+		return nil
+	}
+	for _, name := range names {
+		schema := "imsqti_v2p1.xsd"
+		if name == "imsmanifest.xml" {
+			schema = "imscp_v1p1.xsd"
+		} else if filepath.Ext(name) != ".xml" {
+			continue
+		}
+		if err := validate(schema, filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s isn't valid: %v", name, err)
+		}
+	}
+
+	// The schema catches what the export must not write.
+	invalid := filepath.Join(dir, "invalid.xml")
+	item := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="x" title="x"` +
+		` adaptive="false" timeDependent="false"><itemBody><p>a<ul><li>b</li></ul></p>` +
+		`<table><tr><td>c</td></tr></table></itemBody></assessmentItem>`
+	if err := os.WriteFile(invalid, []byte(item), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if validate("imsqti_v2p1.xsd", invalid) == nil {
+		t.Error("a list in a paragraph and a table without tbody passed the schema")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Reference is a link to documentation given in an explanation.
+type Reference struct {
+	Title string
+	URL   string
+}
+
+type References []Reference
+
+var (
+	referencesHeading = regexp.MustCompile(
+		`(?i)(?:<(?:p|div|h[1-6]|b|strong)>\s*)+references?\s*:?\s*(?:</(?:p|div|h[1-6]|b|strong)>\s*)+`,
+	)
+	// referenceList matches the links listed after the references heading,
+	// one per line or paragraph or as a list.
+	referenceList = regexp.MustCompile(
+		`^(?s:\s*(?:<br>|<(?:ul|ol)[^>]*>(?:\s*<li>\s*<a[^>]*>.*?</a>\s*</li>)+\s*</(?:ul|ol)>|(?:<(?:p|div|li)>\s*)?<a[^>]*>.*?</a>(?:\s*</(?:p|div|li)>)?))+`,
+	)
+	anchor     = regexp.MustCompile(`(?s)<a(?: href="([^"]*)")?>(.*?)</a>`)
+	anyTag     = regexp.MustCompile(`<[^>]*>`)
+	trailingBr = regexp.MustCompile(`(?:\s*<br>)+\s*$`)
+)
+
+// extractReferences returns the links of a sanitized explanation and the
+// explanation without the heading and list of its references. Text following
+// the list is kept.
+func extractReferences(explanation string) (string, References) {
+	var refs References
+	seen := make(map[string]bool)
+
+	for _, m := range anchor.FindAllStringSubmatch(explanation, -1) {
+		ref, ok := cleanReference(m[1], m[2])
+		if ok && !seen[ref.URL] {
+			seen[ref.URL] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	if loc := referencesHeading.FindStringIndex(explanation); loc != nil {
+		rest := explanation[loc[1]:]
+		rest = strings.TrimSpace(rest[len(referenceList.FindString(rest)):])
+		explanation = trailingBr.ReplaceAllString(explanation[:loc[0]], "")
+		explanation = strings.TrimSpace(explanation)
+		if rest != "" {
+			explanation = strings.TrimSpace(explanation + "\n" + rest)
+		}
+	}
+	return explanation, refs
+}
+
+// cleanReference turns an anchor into a reference with a plain title and a
+// URL without tracking parameters.
+func cleanReference(href string, content string) (Reference, bool) {
+	u, err := url.Parse(strings.TrimSpace(html.UnescapeString(href)))
+	if err != nil || u.Host == "" {
+		return Reference{}, false
+	}
+	u.Host = strings.ToLower(u.Host)
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	title := html.UnescapeString(anyTag.ReplaceAllString(content, ""))
+	title = strings.TrimSpace(whitespace.ReplaceAllString(title, " "))
+	if title == "" {
+		title = u.String()
+	}
+	return Reference{Title: title, URL: u.String()}, true
+}
+
+func (refs References) HTML() string {
+	if len(refs) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(`<ul class="references">`)
+	for _, ref := range refs {
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a></li>`,
+			escapeTemplate(html.EscapeString(ref.URL)),
+			escapeTemplate(html.EscapeString(ref.Title)),
+		)
+	}
+	b.WriteString(`</ul>`)
+	return b.String()
+}
+
+// markdownTitle escapes what would end the text of a Markdown link.
+func markdownTitle(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// AddReferences adds the references of a question to its skill group.
+func (r *CoverageReport) AddReferences(group SkillGroup, refs References) {
+	g := r.group(group)
+	for _, ref := range refs {
+		if !slices.ContainsFunc(g.References, func(other Reference) bool {
+			return other.URL == ref.URL
+		}) {
+			g.References = append(g.References, ref)
+		}
+	}
+}
+
+// WriteReadingList writes the references of all skill groups as a Markdown
+// reading list, each link listed only once.
+func (r *CoverageReport) WriteReadingList(path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Reading list for %s\n", r.Test)
+
+	seen := make(map[string]bool)
+	for _, g := range r.SkillGroups {
+		var refs References
+		for _, ref := range g.References {
+			if !seen[ref.URL] {
+				seen[ref.URL] = true
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n## %s\n\n", g.Name)
+		for _, ref := range refs {
+			fmt.Fprintf(&b, "- [%s](<%s>)\n", markdownTitle(ref.Title), ref.URL)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
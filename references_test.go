@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestExtractReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		explanation string
+		want        string
+		refs        References
+	}{
+		{
+			"no references",
+			"<p>Because.</p>",
+			"<p>Because.</p>",
+			nil,
+		},
+		{
+			"links after the heading",
+			`<p>Because.</p><br><p><b>References:</b></p><p><a href="https://a.com/x">X</a></p>` +
+				`<p><a href="https://b.com/y">Y</a></p>`,
+			"<p>Because.</p>",
+			References{{"X", "https://a.com/x"}, {"Y", "https://b.com/y"}},
+		},
+		{
+			"list after the heading",
+			`Because.<br><b>Reference</b> <ul><li><a href="https://a.com/x">X</a></li></ul>`,
+			"Because.",
+			References{{"X", "https://a.com/x"}},
+		},
+		{
+			"text after the links",
+			`<p>Because.</p><p>References</p><a href="https://a.com/x">X</a><br>` +
+				`<a href="https://b.com/y">Y</a><br><p>Note that Y is in preview.</p>`,
+			"<p>Because.</p>\n<p>Note that Y is in preview.</p>",
+			References{{"X", "https://a.com/x"}, {"Y", "https://b.com/y"}},
+		},
+		{
+			"link within the text",
+			`<p>See <a href="https://a.com/x">X</a>.</p><h3>References:</h3><p>Read the docs.</p>`,
+			`<p>See <a href="https://a.com/x">X</a>.</p>` + "\n<p>Read the docs.</p>",
+			References{{"X", "https://a.com/x"}},
+		},
+		{
+			"duplicates and relative links",
+			`<a href="https://a.com/x">X</a> <a href="https://A.com/x">X again</a> <a href="/local">L</a>`,
+			`<a href="https://a.com/x">X</a> <a href="https://A.com/x">X again</a> <a href="/local">L</a>`,
+			References{{"X", "https://a.com/x"}},
+		},
+	}
+	for _, tt := range tests {
+		got, refs := extractReferences(tt.explanation)
+		if got != tt.want {
+			t.Errorf("%s: explanation = %q, want %q", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(refs, tt.refs) {
+			t.Errorf("%s: references = %v, want %v", tt.name, refs, tt.refs)
+		}
+	}
+}
+
+func TestCleanReference(t *testing.T) {
+	tests := []struct {
+		href    string
+		content string
+		want    Reference
+		ok      bool
+	}{
+		{"https://Learn.Microsoft.com/en-us/a", "Docs", Reference{"Docs", "https://learn.microsoft.com/en-us/a"}, true},
+		{"https://a.com/x?utm_source=mu&amp;id=1&amp;UTM_Medium=m", "X", Reference{"X", "https://a.com/x?id=1"}, true},
+		{"https://a.com/x", " <b>Bold</b>\n  &amp; more ", Reference{"Bold & more", "https://a.com/x"}, true},
+		{"https://a.com/x", "", Reference{"https://a.com/x", "https://a.com/x"}, true},
+		{"/relative", "R", Reference{}, false},
+		{"", "Nothing", Reference{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := cleanReference(tt.href, tt.content)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("cleanReference(%q, %q) = %v, %v, want %v, %v", tt.href, tt.content, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReferencesHTML(t *testing.T) {
+	refs := References{{"A `b` & c", "https://a.com/x?a=1&b=2"}}
+	want := `<ul class="references"><li><a href="https://a.com/x?a=1&amp;b=2">A &#96;b&#96; &amp; c</a></li></ul>`
+	if got := refs.HTML(); got != want {
+		t.Errorf("HTML() = %s, want %s", got, want)
+	}
+	if got := References(nil).HTML(); got != "" {
+		t.Errorf("HTML() of no references = %q, want nothing", got)
+	}
+}
+
+func TestWriteReadingList(t *testing.T) {
+	report := NewCoverageReport("t1")
+	report.AddReferences(SkillGroup{ID: 1, Name: "One"}, References{{"A [1]", "https://a.com"}, {"B", "https://b.com"}})
+	report.AddReferences(SkillGroup{ID: 1, Name: "One"}, References{{"A again", "https://a.com"}})
+	report.AddReferences(SkillGroup{ID: 2, Name: "Two"}, References{{"B", "https://b.com"}})
+	report.AddReferences(SkillGroup{ID: 3, Name: "Three"}, References{{"C", "https://c.com/a b"}})
+
+	path := t.TempDir() + "/references.md"
+	if err := report.WriteReadingList(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Reading list for t1
+
+## One
+
+- [A \[1\]](<https://a.com>)
+- [B](<https://b.com>)
+
+## Three
+
+- [C](<https://c.com/a b>)
+`
+	if string(got) != want {
+		t.Errorf("reading list:\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+)
+
+type TypeCoverage struct {
+	Converted  int
+	Skipped    int
+	SkippedIDs []string `json:",omitempty"`
+}
+
+type GroupCoverage struct {
+	ID         int
+	Name       string
+	Converted  int
+	Skipped    int
+	SkippedIDs []string   `json:",omitempty"`
+	References References `json:",omitempty"`
+}
+
+// QuestionError is why a question of a supported type couldn't be converted.
+type QuestionError struct {
+	Question string
+	Error    string
+}
+
+type CoverageReport struct {
+	Test         string
+	Converted    int
+	Skipped      int
+	Coverage     float64
+	Types        map[string]*TypeCoverage
+	SkillGroups  []*GroupCoverage
+	MissingMedia []MissingMedia
+	// UnresolvedTexts are the keys which weren't found in the TextDB.
+	UnresolvedTexts []TextDBMiss
+	// Errors are the skipped questions which failed to convert.
+	Errors []QuestionError `json:",omitempty"`
+}
+
+func NewCoverageReport(testName string) *CoverageReport {
+	return &CoverageReport{
+		Test:     testName,
+		Coverage: 1,
+		Types:    make(map[string]*TypeCoverage),
+	}
+}
+
+func (r *CoverageReport) group(group SkillGroup) *GroupCoverage {
+	idx := slices.IndexFunc(r.SkillGroups, func(g *GroupCoverage) bool {
+		return g.ID == group.ID
+	})
+	if idx < 0 {
+		r.SkillGroups = append(r.SkillGroups, &GroupCoverage{
+			ID:   group.ID,
+			Name: group.Name,
+		})
+		idx = len(r.SkillGroups) - 1
+	}
+	return r.SkillGroups[idx]
+}
+
+func (r *CoverageReport) typ(questionType string) *TypeCoverage {
+	tc, ok := r.Types[questionType]
+	if !ok {
+		tc = &TypeCoverage{}
+		r.Types[questionType] = tc
+	}
+	return tc
+}
+
+func (r *CoverageReport) update() {
+	if total := r.Converted + r.Skipped; total > 0 {
+		r.Coverage = float64(r.Converted) / float64(total)
+	}
+}
+
+func (r *CoverageReport) Convert(group SkillGroup, questionType string) {
+	r.Converted++
+	r.typ(questionType).Converted++
+	r.group(group).Converted++
+	r.update()
+}
+
+func (r *CoverageReport) Skip(group SkillGroup, questionType string, id string) {
+	r.Skipped++
+	tc := r.typ(questionType)
+	tc.Skipped++
+	tc.SkippedIDs = append(tc.SkippedIDs, id)
+	gc := r.group(group)
+	gc.Skipped++
+	gc.SkippedIDs = append(gc.SkippedIDs, id)
+	r.update()
+}
+
+// Fail skips a question which failed to convert and records why.
+func (r *CoverageReport) Fail(group SkillGroup, questionType string, id string, err error) {
+	r.Skip(group, questionType, id)
+	r.Errors = append(r.Errors, QuestionError{Question: id, Error: err.Error()})
+}
+
+// LostGroups returns the skill groups which had at least one question skipped.
+func (r *CoverageReport) LostGroups() []*GroupCoverage {
+	var res []*GroupCoverage
+	for _, g := range r.SkillGroups {
+		if g.Skipped > 0 {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func (r *CoverageReport) WriteJSON(path string) error {
+	buf, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
+
+func (r *CoverageReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "Coverage of %s: %d converted, %d skipped (%.1f%%)\n",
+		r.Test, r.Converted, r.Skipped, r.Coverage*100)
+
+	var types []string
+	for t := range r.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		tc := r.Types[t]
+		fmt.Fprintf(w, "  %-20s %4d converted %4d skipped\n", t, tc.Converted, tc.Skipped)
+		for _, id := range tc.SkippedIDs {
+			fmt.Fprintf(w, "    - %s\n", id)
+		}
+	}
+
+	if lost := r.LostGroups(); len(lost) > 0 {
+		fmt.Fprintln(w, "Skill groups that lost coverage:")
+		for _, g := range lost {
+			fmt.Fprintf(w, "  %s: %d of %d skipped\n", g.Name, g.Skipped, g.Converted+g.Skipped)
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(w, "Failed questions:")
+		for _, e := range r.Errors {
+			fmt.Fprintf(w, "  %s: %s\n", e.Question, e.Error)
+		}
+	}
+
+	r.PrintMissingMedia(w)
+	r.PrintUnresolvedTexts(w)
+}
+
+func (r *CoverageReport) PrintMissingMedia(w io.Writer) {
+	if len(r.MissingMedia) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Missing media:")
+	for _, m := range r.MissingMedia {
+		fmt.Fprintf(w, "  %s: %s (%s)\n", m.Question, m.Path, m.Error)
+	}
+}
+
+func (r *CoverageReport) PrintUnresolvedTexts(w io.Writer) {
+	if len(r.UnresolvedTexts) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Unresolved texts:")
+	for _, miss := range r.UnresolvedTexts {
+		fmt.Fprintf(w, "  %s: %s (%s)\n", miss.Question, miss.Key, miss.Field)
+	}
+}
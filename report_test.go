@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCoverageReport(t *testing.T) {
+	one := SkillGroup{ID: 1, Name: "One"}
+	two := SkillGroup{ID: 2, Name: "Two"}
+
+	r := NewCoverageReport("t")
+	if r.Coverage != 1 {
+		t.Errorf("coverage of an empty report = %v, want 1", r.Coverage)
+	}
+
+	r.Convert(one, "singleChoice")
+	r.Convert(one, "singleChoice")
+	r.Convert(two, "multipleChoice")
+	r.Skip(two, "unknownType", "q4")
+
+	if r.Converted != 3 || r.Skipped != 1 {
+		t.Errorf("converted, skipped = %d, %d, want 3, 1", r.Converted, r.Skipped)
+	}
+	if r.Coverage != 0.75 {
+		t.Errorf("coverage = %v, want 0.75", r.Coverage)
+	}
+	if tc := r.Types["unknownType"]; tc == nil || !slices.Equal(tc.SkippedIDs, []string{"q4"}) {
+		t.Errorf("skipped IDs of unknownType = %v, want [q4]", tc)
+	}
+	if tc := r.Types["singleChoice"]; tc == nil || tc.Converted != 2 {
+		t.Errorf("singleChoice = %v, want 2 converted", tc)
+	}
+
+	lost := r.LostGroups()
+	if len(lost) != 1 || lost[0].Name != "Two" || lost[0].Skipped != 1 {
+		t.Errorf("lost groups = %v, want only Two", lost)
+	}
+
+	var b strings.Builder
+	r.Print(&b)
+	for _, want := range []string{"3 converted, 1 skipped (75.0%)", "- q4", "Two: 1 of 2 skipped"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("printed report lacks %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestCoverageReportFail(t *testing.T) {
+	r := NewCoverageReport("t")
+	r.Fail(SkillGroup{ID: 1, Name: "One"}, "hotspot", "q1", errors.New("hotspot has no image"))
+
+	if r.Skipped != 1 || r.Coverage != 0 {
+		t.Errorf("skipped, coverage = %d, %v, want 1, 0", r.Skipped, r.Coverage)
+	}
+	if want := []QuestionError{{"q1", "hotspot has no image"}}; !slices.Equal(r.Errors, want) {
+		t.Errorf("errors = %v, want %v", r.Errors, want)
+	}
+}
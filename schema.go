@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+// SchemaVersion is the version of the JSON export. It's increased whenever a
+// field changes in an incompatible way, new fields may be added without.
+const SchemaVersion = 1
+
+// ExportTest is the document written by -format json.
+type ExportTest struct {
+	Schema    int              `json:"schema"`
+	Test      string           `json:"test"`
+	Language  string           `json:"language,omitempty"`
+	Questions []ExportQuestion `json:"questions"`
+}
+
+type ExportSkillGroup struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ExportImage is an image, Path is relative to the exported file.
+type ExportImage struct {
+	Path string `json:"path"`
+	Alt  string `json:"alt,omitempty"`
+	// Original is the full-size image if the image was downscaled.
+	Original string `json:"original,omitempty"`
+}
+
+type ExportExhibit struct {
+	// Kind is one of image, text, table, code or tabs.
+	Kind     string             `json:"kind"`
+	Image    *ExportImage       `json:"image,omitempty"`
+	Text     string             `json:"text,omitempty"`
+	Language string             `json:"language,omitempty"`
+	Header   []string           `json:"header,omitempty"`
+	Rows     [][]string         `json:"rows,omitempty"`
+	Tabs     []ExportExhibitTab `json:"tabs,omitempty"`
+}
+
+type ExportExhibitTab struct {
+	Label    string          `json:"label"`
+	Exhibits []ExportExhibit `json:"exhibits"`
+}
+
+type ExportCaseStudy struct {
+	ID   string               `json:"id"`
+	Tabs []ExportCaseStudyTab `json:"tabs"`
+}
+
+type ExportCaseStudyTab struct {
+	Label  string        `json:"label"`
+	Text   string        `json:"text"`
+	Images []ExportImage `json:"images,omitempty"`
+}
+
+type ExportReference struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ExportOption is an option to choose, order or drag, its ID is its 1-based
+// position.
+type ExportOption struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// ExportSelect is a dropdown of a liveScreen or a zone of a selectPlaceMup,
+// Correct is the 0-based index of the correct option or null if unknown.
+type ExportSelect struct {
+	Screen  int      `json:"screen,omitempty"`
+	Options []string `json:"options"`
+	Correct *int     `json:"correct"`
+}
+
+// ExportTarget is a target of a dragDrop with the IDs of the options which
+// belong to it.
+type ExportTarget struct {
+	Text    string `json:"text"`
+	Sources []int  `json:"sources"`
+}
+
+// ExportStatement is a statement of a contentTable, questions may consist of
+// several tables.
+type ExportStatement struct {
+	Table   int    `json:"table"`
+	Text    string `json:"text"`
+	Correct bool   `json:"correct"`
+}
+
+// ExportRegion is a region of a hotspot in pixels of its image.
+type ExportRegion struct {
+	X       int  `json:"x"`
+	Y       int  `json:"y"`
+	Width   int  `json:"width"`
+	Height  int  `json:"height"`
+	Correct bool `json:"correct"`
+}
+
+// ExportQuestion is a question, texts are HTML without the escapes of the card
+// scripts. Which of the answer fields are set depends on Kind:
+//
+//   - singleChoice, multipleChoice: options and correct
+//   - buildList: options and order
+//   - dragDrop: options and targets
+//   - liveScreen: screens and selects
+//   - selectPlaceMup: image and selects
+//   - contentTable: statements
+//   - hotspot: image, answerImage and regions
+type ExportQuestion struct {
+	// Schema and Language are only set in -format jsonl, where every line
+	// stands alone.
+	Schema      int               `json:"schema,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Test        string            `json:"test"`
+	ID          string            `json:"id"`
+	Kind        string            `json:"kind"`
+	SkillGroup  ExportSkillGroup  `json:"skillGroup"`
+	Tags        []string          `json:"tags"`
+	Text        string            `json:"text"`
+	Explanation string            `json:"explanation"`
+	References  []ExportReference `json:"references"`
+	Exhibits    []ExportExhibit   `json:"exhibits"`
+	CaseStudy   *ExportCaseStudy  `json:"caseStudy,omitempty"`
+	Translation string            `json:"translation,omitempty"`
+
+	Options     []ExportOption    `json:"options,omitempty"`
+	Correct     []int             `json:"correct,omitempty"`
+	Order       []int             `json:"order,omitempty"`
+	Targets     []ExportTarget    `json:"targets,omitempty"`
+	Screens     []ExportImage     `json:"screens,omitempty"`
+	Selects     []ExportSelect    `json:"selects,omitempty"`
+	Statements  []ExportStatement `json:"statements,omitempty"`
+	Image       *ExportImage      `json:"image,omitempty"`
+	AnswerImage *ExportImage      `json:"answerImage,omitempty"`
+	Regions     []ExportRegion    `json:"regions,omitempty"`
+}
+
+func exportImage(image QuestionImage, media string) *ExportImage {
+	if image.Name == "" {
+		return nil
+	}
+	res := &ExportImage{
+		Path: media + "/" + image.Name,
+		Alt:  image.Alt,
+	}
+	if image.Original != nil {
+		res.Original = media + "/" + image.Original.Name
+	}
+	return res
+}
+
+func exportExhibits(exhibits Exhibits, media string) []ExportExhibit {
+	res := []ExportExhibit{}
+	for _, e := range exhibits {
+		ee := ExportExhibit{
+			Kind:     string(e.Kind),
+			Text:     e.Text,
+			Language: e.Language,
+			Header:   unescapeTemplates(e.Header),
+		}
+		if e.Kind != ExhibitCode {
+			ee.Text = unescapeTemplate(e.Text)
+		}
+		for _, row := range e.Rows {
+			ee.Rows = append(ee.Rows, unescapeTemplates(row))
+		}
+		if e.Kind == ExhibitImage {
+			ee.Image = exportImage(e.Image, media)
+		}
+		for _, tab := range e.Tabs {
+			ee.Tabs = append(ee.Tabs, ExportExhibitTab{
+				Label:    unescapeTemplate(tab.Label),
+				Exhibits: exportExhibits(tab.Exhibits, media),
+			})
+		}
+		res = append(res, ee)
+	}
+	return res
+}
+
+func exportOptions(options []string) []ExportOption {
+	var res []ExportOption
+	for i, opt := range options {
+		res = append(res, ExportOption{ID: i + 1, Text: unescapeTemplate(opt)})
+	}
+	return res
+}
+
+func exportSelect(screen int, options []string, answer int) ExportSelect {
+	sel := ExportSelect{Screen: screen, Options: unescapeTemplates(options)}
+	if answer >= 0 && answer < len(options) {
+		sel.Correct = &answer
+	}
+	return sel
+}
+
+// exportQuestion turns a record into a question of the schema, linking its
+// images relative to the given media folder.
+func exportQuestion(testName string, record Record, media string) ExportQuestion {
+	parts := partsOf(record)
+
+	q := ExportQuestion{
+		Test: testName,
+		ID:   parts.ID,
+		Kind: parts.Kind,
+		SkillGroup: ExportSkillGroup{
+			ID:   parts.Group.ID,
+			Name: parts.Group.Name,
+		},
+		Tags:        append([]string{}, record.Tags()...),
+		Text:        unescapeTemplate(parts.Text),
+		Explanation: unescapeTemplate(parts.Explanation),
+		References:  []ExportReference{},
+		Exhibits:    exportExhibits(parts.Exhibits, media),
+		Translation: unescapeTemplate(parts.Context.Translation),
+	}
+	for _, ref := range parts.Context.Refs {
+		q.References = append(q.References, ExportReference{Title: ref.Title, URL: ref.URL})
+	}
+	if cs := parts.Context.CaseStudy; cs != nil {
+		q.CaseStudy = &ExportCaseStudy{ID: cs.ID, Tabs: []ExportCaseStudyTab{}}
+		for _, tab := range cs.Tabs {
+			t := ExportCaseStudyTab{
+				Label: unescapeTemplate(tab.Label),
+				Text:  unescapeTemplate(tab.Text),
+			}
+			for _, image := range tab.Images {
+				if ei := exportImage(image, media); ei != nil {
+					t.Images = append(t.Images, *ei)
+				}
+			}
+			q.CaseStudy.Tabs = append(q.CaseStudy.Tabs, t)
+		}
+	}
+
+	switch r := record.(type) {
+	case *SingleChoice:
+		q.Options = exportOptions(r.Options)
+		if r.Answer > 0 {
+			q.Correct = []int{r.Answer}
+		}
+	case *MultipleChoice:
+		q.Options = exportOptions(r.Options)
+		q.Correct = r.Answers
+	case *BuildList:
+		q.Options = exportOptions(r.Options)
+		q.Order = r.Answers
+	case *DragDrop:
+		q.Options = exportOptions(r.Options)
+		for _, a := range r.Answers {
+			q.Targets = append(q.Targets, ExportTarget{
+				Text:    unescapeTemplate(a.Target),
+				Sources: a.Sources,
+			})
+		}
+	case *LiveScreen:
+		n := 0
+		for i, screen := range r.Screens {
+			if ei := exportImage(screen.Image, media); ei != nil {
+				q.Screens = append(q.Screens, *ei)
+			} else {
+				q.Screens = append(q.Screens, ExportImage{})
+			}
+			for j := 0; j < screen.Dropdowns; j++ {
+				q.Selects = append(q.Selects, exportSelect(i+1, r.Options[n], r.Answers[n]))
+				n++
+			}
+		}
+	case *SelectPlaceMup:
+		q.Image = exportImage(r.Image, media)
+		for i, opts := range r.Options {
+			q.Selects = append(q.Selects, exportSelect(0, opts, r.Answers[i]))
+		}
+	case *ContentTable:
+		for i, stmts := range r.Statements {
+			for j, stmt := range stmts {
+				q.Statements = append(q.Statements, ExportStatement{
+					Table:   i + 1,
+					Text:    unescapeTemplate(stmt),
+					Correct: r.Answers[i][j],
+				})
+			}
+		}
+	case *HotSpot:
+		q.Image = exportImage(r.Image, media)
+		if r.AnswerImageName != "" {
+			q.AnswerImage = exportImage(QuestionImage{Name: r.AnswerImageName, Alt: r.Image.Alt}, media)
+		}
+		for i := range r.Regions {
+			region := r.ImageRegion(i)
+			q.Regions = append(q.Regions, ExportRegion{
+				X:      region.Min.X,
+				Y:      region.Min.Y,
+				Width:  region.Dx(),
+				Height: region.Dy(),
+			})
+		}
+		for _, i := range r.Answers {
+			if i >= 0 && i < len(q.Regions) {
+				q.Regions[i].Correct = true
+			}
+		}
+	}
+	return q
+}
+
+// writeExport writes all records as a single document, or as one question
+// per line if lines is set.
+func writeExport(path string, testName string, language string, records []Record, media string, lines bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if lines {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, record := range records {
+			q := exportQuestion(testName, record, media)
+			q.Schema = SchemaVersion
+			q.Language = language
+			if err := enc.Encode(q); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}
+
+	doc := ExportTest{
+		Schema:    SchemaVersion,
+		Test:      testName,
+		Language:  language,
+		Questions: []ExportQuestion{},
+	}
+	for _, record := range records {
+		doc.Questions = append(doc.Questions, exportQuestion(testName, record, media))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"image"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportQuestion(t *testing.T) {
+	group := SkillGroup{ID: 3, Name: "Group"}
+	sc := &SingleChoice{
+		ID: "1", Group: group,
+		Text:        sanitizeHTML("Run `dir C:\\Temp` with ${x}"),
+		Explanation: "Because.",
+		Exhibits: Exhibits{
+			{Kind: ExhibitTable, Header: []string{sanitizeHTML("a`b")}, Rows: [][]string{{sanitizeHTML(`\\server`)}}},
+			{Kind: ExhibitCode, Text: "echo `a` \\n", Language: "sh"},
+		},
+		Options: []string{sanitizeHTML(`C:\Windows`), "B"},
+		Answer:  1,
+	}
+	sc.Refs = References{{"Docs", "https://a.com"}}
+	sc.SetCaseStudy(&CaseStudy{ID: "cs1", Tabs: []CaseStudyTab{{Label: "Tab", Text: sanitizeHTML("x`y")}}})
+
+	q := exportQuestion("T1", sc, "media")
+	if want := "Run `dir C:\\Temp` with ${x}"; q.Text != want {
+		t.Errorf("text = %q, want %q", q.Text, want)
+	}
+	if want := []ExportOption{{1, `C:\Windows`}, {2, "B"}}; !reflect.DeepEqual(q.Options, want) {
+		t.Errorf("options = %v, want %v", q.Options, want)
+	}
+	if !reflect.DeepEqual(q.Correct, []int{1}) {
+		t.Errorf("correct = %v, want [1]", q.Correct)
+	}
+	if q.Exhibits[0].Header[0] != "a`b" || q.Exhibits[0].Rows[0][0] != `\\server` {
+		t.Errorf("table = %q %q, want the cells unescaped", q.Exhibits[0].Header, q.Exhibits[0].Rows)
+	}
+	if q.Exhibits[1].Text != "echo `a` \\n" {
+		t.Errorf("code = %q, want it as it is", q.Exhibits[1].Text)
+	}
+	if q.CaseStudy == nil || q.CaseStudy.Tabs[0].Text != "x`y" {
+		t.Errorf("case study = %+v, want the tab unescaped", q.CaseStudy)
+	}
+	if !reflect.DeepEqual(q.Tags, []string{"casestudy::cs1"}) {
+		t.Errorf("tags = %v, want the case study", q.Tags)
+	}
+	if !reflect.DeepEqual(q.References, []ExportReference{{"Docs", "https://a.com"}}) {
+		t.Errorf("references = %v, want the link", q.References)
+	}
+
+	buf, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, escape := range []string{"&#92;", "&#96;", "$&#123;"} {
+		if strings.Contains(string(buf), escape) {
+			t.Errorf("export contains %s: %s", escape, buf)
+		}
+	}
+}
+
+func TestExportHotSpotRegions(t *testing.T) {
+	hs := &HotSpot{
+		ID: "1",
+		Image: QuestionImage{
+			Name:     "screen.png",
+			Original: &MediaOriginal{Name: "screen-full.png", Width: 2000, Height: 1000, ScaledWidth: 1000},
+		},
+		AnswerImageName: "screen-answer.png",
+		Regions:         []image.Rectangle{image.Rect(0, 0, 100, 50), image.Rect(400, 200, 600, 300)},
+		Answers:         []int{1, 5},
+	}
+
+	q := exportQuestion("T1", hs, "media")
+	want := []ExportRegion{
+		{X: 0, Y: 0, Width: 50, Height: 25},
+		{X: 200, Y: 100, Width: 100, Height: 50, Correct: true},
+	}
+	if !reflect.DeepEqual(q.Regions, want) {
+		t.Errorf("regions = %+v, want %+v", q.Regions, want)
+	}
+	if q.Image == nil || q.Image.Path != "media/screen.png" || q.Image.Original != "media/screen-full.png" {
+		t.Errorf("image = %+v, want the downscaled one with its original", q.Image)
+	}
+	if q.AnswerImage == nil || q.AnswerImage.Path != "media/screen-answer.png" {
+		t.Errorf("answer image = %+v", q.AnswerImage)
+	}
+}
+
+func TestExportSelects(t *testing.T) {
+	ls := &LiveScreen{
+		ID:      "1",
+		Screens: []LiveScreenScreen{{Image: QuestionImage{Name: "s1.png"}, Dropdowns: 2}, {Dropdowns: 1}},
+		Options: [][]string{{"a", "b"}, {"c"}, {"d", "e&#96;"}},
+		Answers: []int{1, -1, 0},
+	}
+
+	q := exportQuestion("T1", ls, "media")
+	one, zero := 1, 0
+	want := []ExportSelect{
+		{Screen: 1, Options: []string{"a", "b"}, Correct: &one},
+		{Screen: 1, Options: []string{"c"}},
+		{Screen: 2, Options: []string{"d", "e`"}, Correct: &zero},
+	}
+	if !reflect.DeepEqual(q.Selects, want) {
+		a, _ := json.Marshal(q.Selects)
+		b, _ := json.Marshal(want)
+		t.Errorf("selects = %s, want %s", a, b)
+	}
+	if len(q.Screens) != 2 || q.Screens[0].Path != "media/s1.png" || q.Screens[1].Path != "" {
+		t.Errorf("screens = %+v, want one per slide", q.Screens)
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	records := []Record{
+		&SingleChoice{ID: "1", Options: []string{"A"}, Answer: 1},
+		&MultipleChoice{ID: "2", Options: []string{"A", "B"}, Answers: []int{1, 2}},
+	}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "t1.jsonl")
+	if err := writeExport(path, "T1", "de", records, "media", true); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var q ExportQuestion
+		if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			t.Fatal(err)
+		}
+		if q.Schema != SchemaVersion || q.Language != "de" || q.Test != "T1" {
+			t.Errorf("line %s lacks the schema, language or test", scanner.Bytes())
+		}
+		ids = append(ids, q.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Errorf("lines = %v, want a question per line", ids)
+	}
+
+	path = filepath.Join(dir, "t1.json")
+	if err := writeExport(path, "T1", "de", records, "media", false); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc ExportTest
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema != SchemaVersion || doc.Language != "de" || len(doc.Questions) != 2 {
+		t.Errorf("document = %+v", doc)
+	}
+	if q := doc.Questions[0]; q.Schema != 0 || q.Language != "" {
+		t.Errorf("questions of the document repeat schema %d and language %q", q.Schema, q.Language)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// mediaRef matches the media files a card refers to, which are its images and
+// the links to the originals of downscaled ones. Other links, e.g. to an
+// anchor, are left alone.
+var mediaRef = regexp.MustCompile(`src="([^"/:]+)"|href="([^"/:]+)" class="full-size"`)
+
+// mediaName returns the name of the file a match of mediaRef refers to.
+func mediaName(m []string) string {
+	return m[1] + m[2]
+}
+
+type syncOptions struct {
+	convertOptions
+	URL    string
+	Deck   string
+	DryRun bool
+	// Strict fails if any text couldn't be resolved.
+	Strict bool
+}
+
+type SyncSummary struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Media     int
+}
+
+func (s SyncSummary) Print(w io.Writer, dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "(dry run) "
+	}
+	fmt.Fprintf(w, "%sNotes: %d added, %d updated, %d unchanged; media files: %d\n",
+		prefix, s.Added, s.Updated, s.Unchanged, s.Media)
+}
+
+func recordFields(record Record) map[string]string {
+	fields := make(map[string]string)
+	values := record.Record()
+	for i, col := range record.NoteType().Columns {
+		fields[col] = values[i]
+	}
+	return fields
+}
+
+func ankiQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func syncNoteTypes(ac *AnkiConnect, records []Record, dryRun bool) error {
+	models, err := ac.ModelNames()
+	if err != nil {
+		return err
+	}
+
+	for _, noteType := range NoteTypes {
+		used := slices.ContainsFunc(records, func(r Record) bool {
+			return r.NoteType().Name == noteType.Name
+		})
+		if !used || slices.Contains(models, noteType.Name) {
+			continue
+		}
+
+		log.Printf("Creating note type %s\n", noteType.Name)
+		if dryRun {
+			continue
+		}
+
+		files, err := CardTemplates(noteType)
+		if err != nil {
+			return err
+		}
+		err = ac.CreateModel(
+			noteType,
+			string(files["front.html"]),
+			string(files["back.html"]),
+			string(files["style.css"]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkedMedia returns the names of the media files the records refer to.
+func linkedMedia(records []Record) []string {
+	var names []string
+	for _, record := range records {
+		for _, value := range record.Record() {
+			for _, m := range mediaRef.FindAllStringSubmatch(value, -1) {
+				if name := mediaName(m); !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func syncMedia(ac *AnkiConnect, media string, records []Record, dryRun bool) (int, error) {
+	names := linkedMedia(records)
+	for _, name := range names {
+		if dryRun {
+			continue
+		}
+
+		buf, err := os.ReadFile(filepath.Join(media, name))
+		if err != nil {
+			return 0, err
+		}
+		if err := ac.StoreMediaFile(name, buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(names), nil
+}
+
+func syncNote(ac *AnkiConnect, deck string, record Record, dryRun bool) (*SyncSummary, error) {
+	fields := recordFields(record)
+	model := record.NoteType().Name
+
+	ids, err := ac.FindNotes(fmt.Sprintf(
+		"deck:%s note:%s ID:%s",
+		ankiQuote(deck),
+		ankiQuote(model),
+		ankiQuote(fields["ID"]),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		if !dryRun {
+			err := ac.AddNote(deck, model, fields, append(record.Tags(), "measureup"))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &SyncSummary{Added: 1}, nil
+	}
+
+	notes, err := ac.NotesInfo(ids[:1])
+	if err != nil {
+		return nil, err
+	} else if len(notes) == 0 {
+		return nil, fmt.Errorf("note %d has vanished", ids[0])
+	}
+
+	changed := false
+	for name, value := range fields {
+		if notes[0].Fields[name].Value != value {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return &SyncSummary{Unchanged: 1}, nil
+	}
+
+	if !dryRun {
+		if err := ac.UpdateNoteFields(notes[0].NoteID, fields); err != nil {
+			return nil, err
+		}
+	}
+	return &SyncSummary{Updated: 1}, nil
+}
+
+// syncAnki pushes the records of a test into Anki via AnkiConnect, adding new
+// notes and updating changed ones by their ID.
+func syncAnki(testName string, opts syncOptions) error {
+	if err := checkLanguages(testName, []string{opts.Language}); err != nil {
+		return err
+	}
+
+	media := filepath.Join("out", "collection.media")
+	if opts.DryRun {
+		// Converting copies the media, which a dry run mustn't leave behind.
+		tmp, err := os.MkdirTemp("", "measureup2csv-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		media = tmp
+	}
+
+	records, report, err := convert(testName, media, opts.convertOptions)
+	if err != nil {
+		return err
+	} else if len(report.MissingMedia) > 0 && !opts.Placeholders {
+		report.PrintMissingMedia(os.Stderr)
+		return fmt.Errorf("%d media files are missing", len(report.MissingMedia))
+	} else if len(report.UnresolvedTexts) > 0 && opts.Strict {
+		report.PrintUnresolvedTexts(os.Stderr)
+		return fmt.Errorf("%d texts couldn't be resolved", len(report.UnresolvedTexts))
+	}
+
+	deck := opts.Deck
+	if deck == "" {
+		deck = strings.ToUpper(testName)
+	}
+
+	summary, err := syncDeck(NewAnkiConnect(opts.URL), deck, media, records, opts.DryRun)
+	if err != nil {
+		return err
+	}
+	summary.Print(os.Stdout, opts.DryRun)
+	return nil
+}
+
+// syncDeck creates the deck and the note types if missing, uploads the media
+// and adds or updates the notes of the records.
+func syncDeck(ac *AnkiConnect, deck string, media string, records []Record, dryRun bool) (SyncSummary, error) {
+	var summary SyncSummary
+
+	decks, err := ac.DeckNames()
+	if err != nil {
+		return summary, err
+	}
+	if !slices.Contains(decks, deck) {
+		log.Printf("Creating deck %s\n", deck)
+		if !dryRun {
+			if err := ac.CreateDeck(deck); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if err := syncNoteTypes(ac, records, dryRun); err != nil {
+		return summary, err
+	}
+
+	summary.Media, err = syncMedia(ac, media, records, dryRun)
+	if err != nil {
+		return summary, err
+	}
+
+	for _, record := range records {
+		res, err := syncNote(ac, deck, record, dryRun)
+		if err != nil {
+			return summary, fmt.Errorf("syncing %s: %v", recordFields(record)["ID"], err)
+		}
+		summary.Added += res.Added
+		summary.Updated += res.Updated
+		summary.Unchanged += res.Unchanged
+	}
+	return summary, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var fakeNoteID = regexp.MustCompile(`ID:"([^"]*)"`)
+
+type fakeNote struct {
+	ID     int64
+	Deck   string
+	Model  string
+	Fields map[string]string
+}
+
+// fakeAnki stands in for AnkiConnect, keeping everything in memory.
+type fakeAnki struct {
+	decks   []string
+	models  []string
+	notes   []*fakeNote
+	media   map[string][]byte
+	actions []string
+}
+
+func (f *fakeAnki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action  string
+		Version int
+		Params  json.RawMessage
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version != ankiConnectVersion {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	f.actions = append(f.actions, req.Action)
+
+	var params struct {
+		Deck      string
+		ModelName string
+		Filename  string
+		Data      []byte
+		Query     string
+		Notes     []int64
+		Note      struct {
+			ID        int64
+			DeckName  string
+			ModelName string
+			Fields    map[string]string
+		}
+	}
+	json.Unmarshal(req.Params, &params)
+
+	var result interface{}
+	switch req.Action {
+	case "deckNames":
+		result = f.decks
+	case "createDeck":
+		f.decks = append(f.decks, params.Deck)
+	case "modelNames":
+		result = f.models
+	case "createModel":
+		f.models = append(f.models, params.ModelName)
+	case "storeMediaFile":
+		f.media[params.Filename] = params.Data
+	case "findNotes":
+		ids := []int64{}
+		if m := fakeNoteID.FindStringSubmatch(params.Query); m != nil {
+			for _, n := range f.notes {
+				if n.Fields["ID"] == m[1] {
+					ids = append(ids, n.ID)
+				}
+			}
+		}
+		result = ids
+	case "notesInfo":
+		var infos []AnkiNoteInfo
+		for _, n := range f.notes {
+			if !slices.Contains(params.Notes, n.ID) {
+				continue
+			}
+			info := AnkiNoteInfo{NoteID: n.ID, ModelName: n.Model}
+			info.Fields = make(map[string]struct {
+				Value string
+				Order int
+			})
+			for name, value := range n.Fields {
+				info.Fields[name] = struct {
+					Value string
+					Order int
+				}{Value: value}
+			}
+			infos = append(infos, info)
+		}
+		result = infos
+	case "addNote":
+		f.notes = append(f.notes, &fakeNote{
+			ID:     int64(len(f.notes) + 1),
+			Deck:   params.Note.DeckName,
+			Model:  params.Note.ModelName,
+			Fields: params.Note.Fields,
+		})
+	case "updateNoteFields":
+		for _, n := range f.notes {
+			if n.ID == params.Note.ID {
+				n.Fields = params.Note.Fields
+			}
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": nil, "error": "unsupported action"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil})
+}
+
+// writes returns the actions which changed something.
+func (f *fakeAnki) writes() []string {
+	var res []string
+	for _, action := range f.actions {
+		switch action {
+		case "deckNames", "modelNames", "findNotes", "notesInfo":
+		default:
+			res = append(res, action)
+		}
+	}
+	return res
+}
+
+func startFakeAnki(t *testing.T) (*fakeAnki, *httptest.Server) {
+	fake := &fakeAnki{media: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func TestSyncAnki(t *testing.T) {
+	writeTestDump(t, nil)
+	fake, server := startFakeAnki(t)
+	opts := syncOptions{URL: server.URL}
+
+	if err := syncAnki("t1", opts); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fake.decks, []string{"T1"}) {
+		t.Errorf("decks = %v, want [T1]", fake.decks)
+	}
+	if !slices.Equal(fake.models, []string{"MeasureUpCard"}) {
+		t.Errorf("models = %v, want [MeasureUpCard]", fake.models)
+	}
+	if len(fake.media) != 1 {
+		t.Errorf("got %d media files, want 1", len(fake.media))
+	}
+	if len(fake.notes) != 1 || fake.notes[0].Fields["ID"] != "1" || fake.notes[0].Fields["Answer"] != "2" {
+		t.Fatalf("notes = %+v, want the note of question 1", fake.notes)
+	}
+
+	// Nothing changed.
+	fake.actions = nil
+	ac := NewAnkiConnect(server.URL)
+	records, _, err := convert("t1", "out/collection.media", opts.convertOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := syncDeck(ac, "T1", "out/collection.media", records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Added != 0 || summary.Updated != 0 || summary.Unchanged != 1 {
+		t.Errorf("summary = %+v, want 1 unchanged", summary)
+	}
+	if slices.Contains(fake.actions, "addNote") || slices.Contains(fake.actions, "updateNoteFields") {
+		t.Errorf("unchanged notes were written: %v", fake.actions)
+	}
+
+	// The note was edited in Anki.
+	fake.notes[0].Fields["Text"] = "Edited"
+	summary, err = syncDeck(ac, "T1", "out/collection.media", records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Updated != 1 || fake.notes[0].Fields["Text"] != "Which one?" {
+		t.Errorf("summary = %+v, text = %q, want the note updated", summary, fake.notes[0].Fields["Text"])
+	}
+	if len(fake.notes) != 1 {
+		t.Errorf("got %d notes, want 1", len(fake.notes))
+	}
+}
+
+func TestSyncAnkiAnchorLink(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"textdb.json": `{"s1": "Which one?", "e1": "See <a href=\"#ex\">the exhibit</a>.", "o1": "A", "o2": "B"}`,
+	})
+	fake, server := startFakeAnki(t)
+
+	if err := syncAnki("t1", syncOptions{URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.media) != 1 {
+		t.Errorf("got %d media files, want 1", len(fake.media))
+	}
+	if len(fake.notes) != 1 || !strings.Contains(fake.notes[0].Fields["Explanation"], `href="#ex"`) {
+		t.Errorf("notes = %+v, want the anchor kept in the explanation", fake.notes)
+	}
+}
+
+func TestSyncAnkiDryRun(t *testing.T) {
+	writeTestDump(t, nil)
+	fake, server := startFakeAnki(t)
+
+	if err := syncAnki("t1", syncOptions{URL: server.URL, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if writes := fake.writes(); len(writes) > 0 {
+		t.Errorf("dry run changed Anki: %v", writes)
+	}
+	if _, err := os.Stat("out/collection.media"); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote the media folder: %v", err)
+	}
+}
+
+func TestSyncAnkiStrict(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"textdb.json": `{"s1": "Which one?", "o1": "A", "o2": "B"}`,
+	})
+	fake, server := startFakeAnki(t)
+
+	err := syncAnki("t1", syncOptions{URL: server.URL, Strict: true})
+	if err == nil || err.Error() != "1 texts couldn't be resolved" {
+		t.Errorf("err = %v, want the unresolved text reported", err)
+	}
+	if len(fake.actions) > 0 {
+		t.Errorf("strict sync talked to Anki: %v", fake.actions)
+	}
+
+	if err := syncAnki("t1", syncOptions{URL: server.URL}); err != nil {
+		t.Errorf("err = %v, want the note synced without -strict", err)
+	}
+}
+
+func TestAnkiConnectErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"result": null, "error": "deck was not found"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewAnkiConnect(server.URL + "/down").DeckNames()
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("err = %v, want the HTTP status", err)
+	}
+	err = NewAnkiConnect(server.URL).CreateDeck("x")
+	if err == nil || err.Error() != "createDeck: deck was not found" {
+		t.Errorf("err = %v, want the error of AnkiConnect", err)
+	}
+}
+
+func TestAnkiQuote(t *testing.T) {
+	if got, want := ankiQuote(`a "b" \c`), `"a \"b\" \\c"`; got != want {
+		t.Errorf("ankiQuote = %s, want %s", got, want)
+	}
+}
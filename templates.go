@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"inc": func(i int) int {
+		return i + 1
+	},
+	"field": func(name string) string {
+		return "{{" + name + "}}"
+	},
+}
+
+var ankiFieldRef = regexp.MustCompile(`{{[#^/]?([^{}]+)}}`)
+
+type templateData struct {
+	NoteType NoteType
+	Options  []string
+}
+
+func newTemplateData(noteType NoteType) templateData {
+	var options []string
+	for _, col := range noteType.Columns {
+		if _, err := strconv.Atoi(strings.TrimPrefix(col, "Option-")); err == nil {
+			options = append(options, col)
+		}
+	}
+	return templateData{
+		NoteType: noteType,
+		Options:  options,
+	}
+}
+
+func renderTemplate(name string, data templateData) ([]byte, error) {
+	src, err := templateFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The templates use different delimiters so that Anki's own {{Field}}
+	// syntax can be written verbatim.
+	tmpl, err := template.New(name).
+		Delims("{%", "%}").
+		Funcs(templateFuncs).
+		Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkFields reports fields referenced by a card template which the note
+// type doesn't have.
+func checkFields(name string, content []byte, noteType NoteType) error {
+	for _, m := range ankiFieldRef.FindAllSubmatch(content, -1) {
+		field := strings.TrimSpace(string(m[1]))
+		found := false
+		for _, col := range noteType.Columns {
+			if col == field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s references unknown field '%s'", name, field)
+		}
+	}
+	return nil
+}
+
+// CardTemplates returns the rendered front, back and style of a note type.
+func CardTemplates(noteType NoteType) (map[string][]byte, error) {
+	data := newTemplateData(noteType)
+	res := make(map[string][]byte)
+
+	for _, name := range []string{"front.html", "back.html"} {
+		path := "templates/" + noteType.Name + "/" + name
+		content, err := renderTemplate(path, data)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFields(path, content, noteType); err != nil {
+			return nil, err
+		}
+		res[name] = content
+	}
+
+	style, err := renderTemplate("templates/style.css", data)
+	if err != nil {
+		return nil, err
+	}
+	res["style.css"] = style
+
+	return res, nil
+}
+
+func writeTemplates(dest string) error {
+	for _, noteType := range NoteTypes {
+		files, err := CardTemplates(noteType)
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Join(dest, noteType.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		for name, content := range files {
+			err := os.WriteFile(filepath.Join(dir, name), content, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCardTemplates(t *testing.T) {
+	for _, noteType := range NoteTypes {
+		files, err := CardTemplates(noteType)
+		if err != nil {
+			t.Errorf("%s: %v", noteType.Name, err)
+			continue
+		}
+		for _, name := range []string{"front.html", "back.html", "style.css"} {
+			content := string(files[name])
+			if content == "" {
+				t.Errorf("%s/%s is empty", noteType.Name, name)
+			} else if strings.Contains(content, "{%") {
+				t.Errorf("%s/%s has unrendered actions", noteType.Name, name)
+			}
+		}
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	noteType := NoteType{Name: "Test", Columns: []string{"Text", "Answer"}}
+
+	if err := checkFields("ok", []byte("{{Text}}{{#Answer}}{{Answer}}{{/Answer}}"), noteType); err != nil {
+		t.Error(err)
+	}
+	if err := checkFields("bad", []byte("{{Text}}{{Missing}}"), noteType); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
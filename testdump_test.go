@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPNG returns an encoded PNG of the given size.
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTestDump writes a dump of the test t1 with a single choice question
+// into a temporary folder and changes into it. files adds to or replaces the
+// files of the dump, an empty content removes one.
+func writeTestDump(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	dump := map[string]string{
+		"skillGroups.json": `[{"ID": 1, "Name": "Group One", "Questions": [
+			{"Name": "MUP/Q_1", "question_type": "singleChoice"}
+		]}]`,
+		"textdb.json": `{"s1": "Which one?", "e1": "Because.", "o1": "A", "o2": "B"}`,
+		"questions/Q_1.json": `{
+			"Stem": {"Value": "$$s1"},
+			"Explanation": {"Value": "$$e1"},
+			"Exhibit": {"Content": [{"image": "img/q1/ex.png", "alt": "Exhibit"}]},
+			"Models": [{"Model": "m", "Correct": "rb2"}],
+			"StartSlide": {"Value": "views/Q_1_s"}
+		}`,
+		"slides/Q_1_s.json": `{"RadioButtons": [
+			{"ID": "rb1", "Value": "$$o1"},
+			{"ID": "rb2", "Value": "$$o2"}
+		]}`,
+		"images/Q_1-ex.png": string(testPNG(t, 8, 4)),
+	}
+	for name, content := range files {
+		dump[name] = content
+	}
+
+	dir := t.TempDir()
+	for name, content := range dump {
+		if content == "" {
+			continue
+		}
+		path := filepath.Join(dir, "out", "dump", "t1", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var languageCode = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,4})?$`)
+
+// textDBFile returns the name of the TextDB file of a language, the default
+// language of the account has none.
+func textDBFile(language string) string {
+	if language == "" {
+		return "textdb.json"
+	}
+	return "textdb." + strings.ToLower(language) + ".json"
+}
+
+// dumpedLanguages returns the languages of the TextDBs in the dump of a test,
+// besides the default one.
+func dumpedLanguages(src string) []string {
+	files, _ := filepath.Glob(filepath.Join(src, "textdb.*.json"))
+
+	var res []string
+	for _, file := range files {
+		name := filepath.Base(file)
+		res = append(res, strings.TrimSuffix(strings.TrimPrefix(name, "textdb."), ".json"))
+	}
+	return res
+}
+
+// checkLanguages fails if the texts of a language weren't dumped for a test.
+func checkLanguages(testName string, languages []string) error {
+	src := filepath.Join("out", "dump", testName)
+	if _, err := os.Stat(src); err != nil {
+		// Reported by convert.
+		return nil
+	}
+
+	available := dumpedLanguages(src)
+	for _, language := range languages {
+		if language == "" || slices.Contains(available, strings.ToLower(language)) {
+			continue
+		} else if len(available) == 0 {
+			return fmt.Errorf(
+				"no texts in '%s' were dumped for '%s', run dump with -lang %s first",
+				language, testName, language)
+		}
+		return fmt.Errorf(
+			"no texts in '%s' were dumped for '%s', only in: %s",
+			language, testName, strings.Join(available, ", "))
+	}
+	return nil
+}
+
+// TextDBMiss is a key which was looked up but isn't in the TextDB.
+type TextDBMiss struct {
+	Question string
+	Field    string `json:",omitempty"`
+	Key      string
+}
+
+// TextDB holds the texts of a test by their key. Lookups of unknown keys are
+// tracked along with the question and field they were made for.
+type TextDB struct {
+	texts    map[string]string
+	question string
+	field    string
+	misses   *[]TextDBMiss
+}
+
+func (db *TextDB) UnmarshalJSON(b []byte) error {
+	db.misses = &[]TextDBMiss{}
+	return json.Unmarshal(b, &db.texts)
+}
+
+// For returns the TextDB to look up the texts of the given question with.
+func (db TextDB) For(question string) TextDB {
+	db.question = question
+	db.field = ""
+	return db
+}
+
+// In returns the TextDB to look up the text of the given field of the current
+// question with, e.g. Stem.Value or RadioButtons[1].Value.
+func (db TextDB) In(field string) TextDB {
+	db.field = field
+	return db
+}
+
+func (db TextDB) Lookup(key string) (string, bool) {
+	text, ok := db.texts[strings.TrimPrefix(key, "$$")]
+	return text, ok
+}
+
+// Get returns the text of a key. Unknown keys are returned as they are, so
+// that they are still visible on the card. Only unknown $$ keys are tracked,
+// anything else is a literal text, e.g. the target "Distractor" of a drag and
+// drop question.
+func (db TextDB) Get(key string) string {
+	if key == "" {
+		return ""
+	} else if text, ok := db.Lookup(key); ok {
+		return normalizeText(text)
+	}
+
+	if db.misses != nil && strings.HasPrefix(key, "$$") {
+		*db.misses = append(*db.misses, TextDBMiss{
+			Question: db.question,
+			Field:    db.field,
+			Key:      key,
+		})
+	}
+	return key
+}
+
+// HTML returns the text of a key sanitized to be put into a card.
+func (db TextDB) HTML(key string) string {
+	return sanitizeHTML(db.Get(key))
+}
+
+// Resolve returns the sanitized text of s if it's a $$ key, otherwise s is
+// taken as the text itself, e.g. the alt texts of images.
+func (db TextDB) Resolve(s string) string {
+	if strings.HasPrefix(s, "$$") {
+		return db.HTML(s)
+	}
+	return sanitizeHTML(s)
+}
+
+// Misses returns every unknown key looked up so far, each one only once per
+// question and field.
+func (db TextDB) Misses() []TextDBMiss {
+	if db.misses == nil {
+		return nil
+	}
+
+	var res []TextDBMiss
+	seen := make(map[TextDBMiss]bool)
+	for _, miss := range *db.misses {
+		if !seen[miss] {
+			seen[miss] = true
+			res = append(res, miss)
+		}
+	}
+	return res
+}
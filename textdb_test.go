@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTextDBGet(t *testing.T) {
+	textDB := testTextDB(t, map[string]string{"k1": "Text one"})
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"$$k1", "Text one"},
+		{"k1", "Text one"},
+		{"", ""},
+		{"$$k2", "$$k2"},
+		{"k3", "k3"},
+		{"Distractor", "Distractor"},
+	}
+	for _, tt := range tests {
+		if got := textDB.In("Stem.Value").Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	want := []TextDBMiss{
+		{Question: "q1", Field: "Stem.Value", Key: "$$k2"},
+	}
+	if got := textDB.Misses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Misses() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTextDBMissesOncePerField(t *testing.T) {
+	textDB := testTextDB(t, nil)
+	textDB.In("Stem.Value").Get("$$k")
+	textDB.In("Stem.Value").HTML("$$k")
+	textDB.In("Explanation.Value").Get("$$k")
+	textDB.For("q2").Get("$$k")
+
+	want := []TextDBMiss{
+		{Question: "q1", Field: "Stem.Value", Key: "$$k"},
+		{Question: "q1", Field: "Explanation.Value", Key: "$$k"},
+		{Question: "q2", Key: "$$k"},
+	}
+	if got := textDB.Misses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Misses() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConvertReportsUnresolvedTexts(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"textdb.json": `{"s1": "Which one?", "o1": "A"}`,
+	})
+
+	_, report, err := convert("t1", "out/collection.media", convertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextDBMiss{
+		{Question: "Q_1", Field: "RadioButtons[1].Value", Key: "$$o2"},
+		{Question: "Q_1", Field: "Explanation.Value", Key: "$$e1"},
+	}
+	if !reflect.DeepEqual(report.UnresolvedTexts, want) {
+		t.Errorf("UnresolvedTexts = %+v, want %+v", report.UnresolvedTexts, want)
+	}
+}
+
+func TestCheckLanguages(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"textdb.de.json": `{"s1": "Welche?"}`,
+		"textdb.en.json": `{"s1": "Which one?"}`,
+	})
+
+	tests := []struct {
+		languages []string
+		want      string
+	}{
+		{nil, ""},
+		{[]string{""}, ""},
+		{[]string{"de", "EN"}, ""},
+		{[]string{"de", "fr"}, "no texts in 'fr' were dumped for 't1', only in: de, en"},
+	}
+	for _, tt := range tests {
+		err := checkLanguages("t1", tt.languages)
+		if got := fmt.Sprint(err); tt.want == "" && err != nil || tt.want != "" && got != tt.want {
+			t.Errorf("checkLanguages(%v) = %v, want %q", tt.languages, err, tt.want)
+		}
+	}
+}
+
+func TestProduceUndumpedLanguage(t *testing.T) {
+	writeTestDump(t, nil)
+
+	err := produce("t1", produceOptions{Format: "csv", Languages: []string{"de"}})
+	want := "no texts in 'de' were dumped for 't1', run dump with -lang de first"
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+	if _, err := os.Stat("out/t1-de.report.json"); !os.IsNotExist(err) {
+		t.Errorf("produce wrote files for the missing language: %v", err)
+	}
+
+	fake, server := startFakeAnki(t)
+	err = syncAnki("t1", syncOptions{URL: server.URL, convertOptions: convertOptions{Language: "de"}})
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+	if len(fake.actions) > 0 {
+		t.Errorf("sync talked to Anki: %v", fake.actions)
+	}
+}
+
+func TestLanguageCode(t *testing.T) {
+	for code, want := range map[string]bool{
+		"de": true, "EN": true, "pt-BR": true, "zh_Hans": true,
+		"": false, "d": false, "../x": false, "de,en": false,
+	} {
+		if got := languageCode.MatchString(code); got != want {
+			t.Errorf("languageCode.MatchString(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Problem is an inconsistency found in a dump.
+type Problem struct {
+	Kind     string
+	Question string `json:",omitempty"`
+	Path     string `json:",omitempty"`
+	Detail   string `json:",omitempty"`
+}
+
+const (
+	ProblemInvalidJSON     = "invalid-json"
+	ProblemMissingQuestion = "missing-question"
+	ProblemMissingSlide    = "missing-slide"
+	ProblemMissingImage    = "missing-image"
+	ProblemBrokenImage     = "broken-image"
+	ProblemUnresolvedKey   = "unresolved-key"
+	ProblemCaseStudy       = "malformed-case-study"
+)
+
+// textKeys collects all TextDB keys used in a decoded JSON value, the ones of
+// an object in the order of its sorted fields.
+func textKeys(v interface{}) []string {
+	var res []string
+
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			res = append(res, v)
+		}
+	case []interface{}:
+		for _, e := range v {
+			res = append(res, textKeys(e)...)
+		}
+	case map[string]interface{}:
+		fields := make([]string, 0, len(v))
+		for field := range v {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			res = append(res, textKeys(v[field])...)
+		}
+	}
+	return res
+}
+
+type validator struct {
+	src      string
+	textDB   TextDB
+	store    *MediaStore
+	problems []Problem
+}
+
+func (v *validator) report(kind string, question string, path string, detail string) {
+	v.problems = append(v.problems, Problem{
+		Kind:     kind,
+		Question: question,
+		Path:     path,
+		Detail:   detail,
+	})
+}
+
+// readFile reads a JSON file of the dump into out and checks its TextDB keys.
+// It reports missing or invalid files and returns whether it succeeded.
+func (v *validator) readFile(question string, path string, missing string, out interface{}) bool {
+	rel, _ := filepath.Rel(v.src, path)
+
+	if _, err := os.Stat(path); err != nil {
+		v.report(missing, question, rel, err.Error())
+		return false
+	}
+
+	var raw interface{}
+	if err := readJSON(path, &raw); err != nil {
+		v.report(ProblemInvalidJSON, question, rel, err.Error())
+		return false
+	}
+	for _, key := range textKeys(raw) {
+		if _, ok := v.textDB.Lookup(key); !ok {
+			v.report(ProblemUnresolvedKey, question, rel, key)
+		}
+	}
+
+	if err := readJSON(path, out); err != nil {
+		v.report(ProblemInvalidJSON, question, rel, err.Error())
+		return false
+	}
+	return true
+}
+
+func (v *validator) checkImage(question string, path string) {
+	file := v.store.File(question, path)
+
+	f, err := os.Open(file)
+	if err != nil {
+		v.report(ProblemMissingImage, question, path, err.Error())
+		return
+	}
+	defer f.Close()
+
+	if _, _, err := image.Decode(f); err != nil {
+		v.report(ProblemBrokenImage, question, path, err.Error())
+	}
+}
+
+func (v *validator) checkQuestion(groupQuestion SkillGroupQuestion) []SkillGroupQuestion {
+	var children []SkillGroupQuestion
+
+	_, qfn, _ := strings.Cut(groupQuestion.Name, "/")
+	qfp := filepath.Join(v.src, "questions", qfn+".json")
+
+	var question Question
+	if !v.readFile(qfn, qfp, ProblemMissingQuestion, &question) {
+		return nil
+	}
+
+	images := question.Images()
+
+	var slides QuestionSlides
+	for _, slideName := range question.SlideNames() {
+		parts := strings.Split(slideName, "/")
+		sfp := filepath.Join(v.src, "slides", parts[len(parts)-1]+".json")
+
+		var slide QuestionSlide
+		if v.readFile(qfn, sfp, ProblemMissingSlide, &slide) {
+			slides = append(slides, slide)
+			images = append(images, slide.AllImages()...)
+		}
+	}
+
+	if groupQuestion.Type == "caseStudy" && len(slides) > 0 {
+		opts, err := slides.Merged().CaseStudyOptions()
+		if err != nil {
+			v.report(ProblemCaseStudy, qfn, "", err.Error())
+		}
+		for _, opt := range opts {
+			if opt.CSContext != "" {
+				children = append(children, SkillGroupQuestion{
+					Name: groupQuestion.Name + "_" + opt.CSContext,
+					Type: "caseStudyQuestion",
+				})
+				continue
+			}
+
+			parts := strings.Split(opt.View, "/")
+			sfp := filepath.Join(v.src, "slides", parts[len(parts)-1]+".json")
+
+			var tab QuestionSlide
+			if v.readFile(qfn, sfp, ProblemMissingSlide, &tab) {
+				images = append(images, tab.AllImages()...)
+			}
+		}
+	}
+
+	var checked []string
+	for _, image := range images {
+		if !slices.Contains(checked, image.Name) {
+			v.checkImage(qfn, image.Name)
+			checked = append(checked, image.Name)
+		}
+	}
+
+	return children
+}
+
+// validate checks a dump for consistency without accessing the network.
+func validate(testName string) ([]Problem, error) {
+	src := filepath.Join("out", "dump", testName)
+
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil, fmt.Errorf("'%s' was not found", testName)
+	} else if err != nil {
+		return nil, fmt.Errorf("accessing '%s': %v", testName, err)
+	}
+
+	var groups []SkillGroup
+	if err := readJSON(filepath.Join(src, "skillGroups.json"), &groups); err != nil {
+		return nil, err
+	}
+
+	v := &validator{src: src}
+	if err := readJSON(filepath.Join(src, "textdb.json"), &v.textDB); err != nil {
+		return nil, err
+	}
+
+	store, err := OpenMediaStore(src)
+	if err != nil {
+		return nil, err
+	}
+	v.store = store
+
+	for _, group := range groups {
+		for i := 0; i < len(group.Questions); i++ {
+			children := v.checkQuestion(group.Questions[i])
+			group.Questions = append(group.Questions, children...)
+		}
+	}
+
+	return v.problems, nil
+}
+
+func printProblems(w io.Writer, problems []Problem) error {
+	if problems == nil {
+		problems = []Problem{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(problems)
+}
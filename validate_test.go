@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	writeTestDump(t, map[string]string{
+		"skillGroups.json": `[{"ID": 1, "Name": "Group One", "Questions": [
+			{"Name": "MUP/Q_1", "question_type": "singleChoice"},
+			{"Name": "MUP/Q_2", "question_type": "singleChoice"},
+			{"Name": "MUP/Q_3", "question_type": "singleChoice"},
+			{"Name": "MUP/Q_4", "question_type": "caseStudy"}
+		]}]`,
+		"questions/Q_2.json": `{
+			"Stem": {"Value": "$$unknown"},
+			"Exhibit": {"Content": [{"image": "img/q2/broken.png"}, {"image": "img/q2/gone.png"}]},
+			"StartSlide": {"Value": "views/Q_2_s"}
+		}`,
+		"images/Q_2-broken.png": "not an image",
+		"questions/Q_3.json":    `{"StartSlide": {"Value": "views/Q_3_s"`,
+		"questions/Q_4.json":    `{"StartSlide": {"Value": "views/Q_4_s"}}`,
+		"slides/Q_4_s.json":     `{"CaseStudy": []}`,
+	})
+
+	problems, err := validate("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Kind: ProblemUnresolvedKey, Question: "Q_2", Path: "questions/Q_2.json", Detail: "$$unknown"},
+		{Kind: ProblemMissingSlide, Question: "Q_2", Path: "slides/Q_2_s.json"},
+		{Kind: ProblemBrokenImage, Question: "Q_2", Path: "img/q2/broken.png"},
+		{Kind: ProblemMissingImage, Question: "Q_2", Path: "img/q2/gone.png"},
+		{Kind: ProblemInvalidJSON, Question: "Q_3", Path: "questions/Q_3.json"},
+		{Kind: ProblemCaseStudy, Question: "Q_4"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %+v, want %d", problems, len(want))
+	}
+	for i, p := range problems {
+		p.Detail, want[i].Detail = detailOf(p, want[i]), detailOf(want[i], want[i])
+		if p != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, p, want[i])
+		}
+	}
+}
+
+// detailOf returns the detail of a problem if the expected one has it, as
+// the others contain error messages of the system.
+func detailOf(p Problem, want Problem) string {
+	if want.Detail == "" {
+		return ""
+	}
+	return p.Detail
+}
+
+func TestTextKeys(t *testing.T) {
+	var raw interface{}
+	err := json.Unmarshal([]byte(`{
+		"Stem": {"Value": "$$s1"},
+		"Explanation": {"Value": "$$e1"},
+		"Models": [{"Correct": "rb1", "Label": "$$m2"}, {"Label": "$$m1"}],
+		"Exhibit": {"Content": [{"alt": "$$a1", "image": "img/q1/ex.png"}]},
+		"StartSlide": {"Value": "views/Q_1_s"}
+	}`), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"$$a1", "$$e1", "$$m2", "$$m1", "$$s1"}
+	for i := 0; i < 20; i++ {
+		if got := textKeys(raw); !slices.Equal(got, want) {
+			t.Fatalf("textKeys = %v, want %v", got, want)
+		}
+	}
+}